@@ -0,0 +1,162 @@
+// Package cas implements a content-addressable blob store on the local
+// filesystem, keyed by the BLAKE-256 checksum of each blob.
+//
+// Blobs are stored under the store's root directory as
+//
+//	objects/ab/cd/abcd...
+//
+// where the two directory levels are the first two bytes of the hex
+// checksum, which keeps directories small. Blobs are written to a
+// temporary file in tmp/ and renamed into place, so a blob is either
+// complete or absent. Blobs found corrupt by Scrub are moved to
+// corrupt/.
+package cas
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/ouzklcn/blake"
+)
+
+// Digest is the key of a blob.
+type Digest = blake.Digest256Value
+
+var (
+	// ErrNotFound is returned for blobs which are not in the store. It
+	// wraps fs.ErrNotExist.
+	ErrNotFound = fmt.Errorf("cas: blob not found: %w", fs.ErrNotExist)
+	// ErrCorrupt is returned when a blob does not match its digest.
+	ErrCorrupt = errors.New("cas: blob does not match its digest")
+)
+
+// Store is a content-addressable store rooted at a directory. It is
+// safe for concurrent use, including by several processes.
+type Store struct {
+	root string
+}
+
+// Open opens the store rooted at dir, creating it if necessary.
+func Open(dir string) (*Store, error) {
+	s := &Store{root: dir}
+	for _, d := range []string{"objects", "tmp", "corrupt"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// path returns the file name of the blob with digest d.
+func (s *Store) path(d Digest) string {
+	h := d.String()
+	return filepath.Join(s.root, "objects", h[:2], h[2:4], h)
+}
+
+// Put stores the content of r and returns its digest. Storing a blob
+// which is already present leaves the existing blob in place.
+func (s *Store) Put(r io.Reader) (Digest, error) {
+	var d Digest
+	f, err := os.CreateTemp(filepath.Join(s.root, "tmp"), "put-")
+	if err != nil {
+		return d, err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	h := blake.New256()
+	_, err = io.Copy(io.MultiWriter(f, h), r)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return d, err
+	}
+	h.Sum(d[:0])
+
+	name := s.path(d)
+	if _, err := os.Stat(name); err == nil {
+		return d, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return d, err
+	}
+	if err := os.Chmod(tmp, 0o444); err != nil {
+		return d, err
+	}
+	return d, os.Rename(tmp, name)
+}
+
+// Get returns a reader for the blob with digest d. The content is
+// verified as it is read, and reading the end of a blob which does not
+// match d returns ErrCorrupt.
+func (s *Store) Get(d Digest) (io.ReadCloser, error) {
+	f, err := os.Open(s.path(d))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &verifier{blake.NewVerifyingReader(f, blake.New256(), d[:]), f}, nil
+}
+
+// Has reports whether the blob with digest d is in the store.
+func (s *Store) Has(d Digest) (bool, error) {
+	_, err := os.Stat(s.path(d))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Delete removes the blob with digest d. It returns ErrNotFound if the
+// blob is not in the store.
+func (s *Store) Delete(d Digest) error {
+	err := os.Remove(s.path(d))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// Walk calls fn for the digest of every blob in the store in ascending
+// order, stopping at the first error fn returns. Files whose names are
+// not digests are skipped.
+func (s *Store) Walk(fn func(Digest) error) error {
+	return filepath.WalkDir(filepath.Join(s.root, "objects"), func(name string, e fs.DirEntry, err error) error {
+		if err != nil || !e.Type().IsRegular() {
+			return err
+		}
+		d, perr := blake.ParseDigest256Hex(e.Name())
+		if perr != nil || s.path(d) != name {
+			return nil
+		}
+		return fn(d)
+	})
+}
+
+// verifier checks a blob against its digest as it is read.
+type verifier struct {
+	*blake.VerifyingReader
+	f *os.File
+}
+
+func (v *verifier) Read(p []byte) (int, error) {
+	n, err := v.VerifyingReader.Read(p)
+	if err == blake.ErrDigestMismatch {
+		err = ErrCorrupt
+	}
+	return n, err
+}
+
+func (v *verifier) Close() error {
+	return v.f.Close()
+}
@@ -0,0 +1,120 @@
+package cas
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ouzklcn/blake"
+)
+
+func TestPutGet(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := s.Put(strings.NewReader("BLAKE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := blake.Digest256Value(blake.Sum256([]byte("BLAKE"))); d != want {
+		t.Errorf("expected %s, got %s", want, d)
+	}
+	if d2, err := s.Put(strings.NewReader("BLAKE")); err != nil || d2 != d {
+		t.Errorf("second Put = %s, %v", d2, err)
+	}
+	if _, err := os.Stat(filepath.Join(s.root, "objects", "07", "66", d.String())); err != nil {
+		t.Errorf("blob not in fan-out layout: %v", err)
+	}
+	if tmp, _ := os.ReadDir(filepath.Join(s.root, "tmp")); len(tmp) != 0 {
+		t.Errorf("temporary files left behind: %v", tmp)
+	}
+
+	r, err := s.Get(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(r)
+	r.Close()
+	if err != nil || string(b) != "BLAKE" {
+		t.Errorf("Get = %q, %v", b, err)
+	}
+
+	if ok, err := s.Has(d); !ok || err != nil {
+		t.Errorf("Has = %v, %v", ok, err)
+	}
+	if err := s.Delete(d); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := s.Has(d); ok {
+		t.Error("deleted blob still present")
+	}
+	if _, err := s.Get(d); !errors.Is(err, ErrNotFound) || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := s.Delete(d); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func corrupt(t *testing.T, s *Store, d Digest) {
+	t.Helper()
+	name := s.path(d)
+	os.Chmod(name, 0o644)
+	if err := os.WriteFile(name, []byte("blake"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetCorrupt(t *testing.T) {
+	s, _ := Open(t.TempDir())
+	d, err := s.Put(strings.NewReader("BLAKE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupt(t, s, d)
+	r, err := s.Get(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := io.ReadAll(r); err != ErrCorrupt {
+		t.Errorf("expected ErrCorrupt, got %v", err)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	s, _ := Open(t.TempDir())
+	var want []Digest
+	for _, v := range []string{"", "ube", "BLAKE", "Golang"} {
+		d, err := s.Put(strings.NewReader(v))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, d)
+	}
+	os.WriteFile(filepath.Join(s.root, "objects", "README"), []byte("x"), 0o644)
+
+	var got []Digest
+	if err := s.Walk(func(d Digest) error { got = append(got, d); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d blobs, got %d", len(want), len(got))
+	}
+	for i := 1; i < len(got); i++ {
+		if bytes.Compare(got[i-1][:], got[i][:]) >= 0 {
+			t.Errorf("digests not in ascending order: %s, %s", got[i-1], got[i])
+		}
+	}
+	stop := errors.New("stop")
+	n := 0
+	if err := s.Walk(func(Digest) error { n++; return stop }); err != stop || n != 1 {
+		t.Errorf("Walk did not stop: %v after %d calls", err, n)
+	}
+}
@@ -0,0 +1,79 @@
+package cas
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ScrubReport describes the outcome of a scrub.
+type ScrubReport struct {
+	Checked int      // number of blobs read
+	Corrupt []Digest // blobs which did not match their digest
+}
+
+// Scrub reads every blob in the store and moves those which do not
+// match their digest to the corrupt/ directory, so that Has reports
+// them missing and they can be stored again. It stops early with the
+// context's error if ctx is done.
+func (s *Store) Scrub(ctx context.Context) (ScrubReport, error) {
+	var rep ScrubReport
+	err := s.Walk(func(d Digest) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ok, err := s.check(d)
+		if errors.Is(err, ErrNotFound) {
+			// Deleted since Walk listed it.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rep.Checked++
+		if !ok {
+			rep.Corrupt = append(rep.Corrupt, d)
+			return os.Rename(s.path(d), filepath.Join(s.root, "corrupt", d.String()))
+		}
+		return nil
+	})
+	return rep, err
+}
+
+// check reports whether the blob with digest d matches it.
+func (s *Store) check(d Digest) (bool, error) {
+	r, err := s.Get(d)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+	_, err = io.Copy(io.Discard, r)
+	if errors.Is(err, ErrCorrupt) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ScrubEvery scrubs the store in the background every interval until
+// ctx is done, passing the outcome of each scrub to report if it is not
+// nil.
+func (s *Store) ScrubEvery(ctx context.Context, interval time.Duration, report func(ScrubReport, error)) {
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+			rep, err := s.Scrub(ctx)
+			if report != nil && ctx.Err() == nil {
+				report(rep, err)
+			}
+		}
+	}()
+}
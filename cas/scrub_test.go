@@ -0,0 +1,65 @@
+package cas
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScrub(t *testing.T) {
+	s, _ := Open(t.TempDir())
+	good, _ := s.Put(strings.NewReader("BLAKE"))
+	bad, _ := s.Put(strings.NewReader("Golang"))
+	corrupt(t, s, bad)
+
+	rep, err := s.Scrub(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Checked != 2 || len(rep.Corrupt) != 1 || rep.Corrupt[0] != bad {
+		t.Errorf("unexpected report %+v", rep)
+	}
+	if ok, _ := s.Has(bad); ok {
+		t.Error("corrupt blob still present")
+	}
+	if ok, _ := s.Has(good); !ok {
+		t.Error("good blob removed")
+	}
+	if d, err := s.Put(strings.NewReader("Golang")); err != nil || d != bad {
+		t.Errorf("cannot store the blob again: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.Scrub(ctx); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestScrubEvery(t *testing.T) {
+	s, _ := Open(t.TempDir())
+	bad, _ := s.Put(strings.NewReader("BLAKE"))
+	corrupt(t, s, bad)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reports := make(chan ScrubReport, 1)
+	s.ScrubEvery(ctx, time.Millisecond, func(rep ScrubReport, err error) {
+		if err != nil {
+			t.Error(err)
+		}
+		select {
+		case reports <- rep:
+		default:
+		}
+	})
+	select {
+	case rep := <-reports:
+		if len(rep.Corrupt) != 1 || rep.Corrupt[0] != bad {
+			t.Errorf("unexpected report %+v", rep)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no scrub report")
+	}
+}
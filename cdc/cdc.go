@@ -0,0 +1,190 @@
+// Package cdc splits streams into content-defined chunks with a
+// FastCDC-style chunker and identifies them by their BLAKE-256
+// checksums for deduplication.
+//
+// Chunk boundaries depend only on the content near them, so inserting
+// or removing bytes changes only the chunks around the edit. Boundaries
+// are found with a gear rolling hash whose table is generated with
+// BLAKE-256 from a salt. With a secret salt, an attacker who does not
+// know it cannot predict where chunks begin, which would otherwise leak
+// information about stored data through chunk sizes.
+package cdc
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/bits"
+
+	"github.com/ouzklcn/blake"
+)
+
+// Default chunk sizes.
+const (
+	DefaultMinSize = 2 << 10
+	DefaultAvgSize = 8 << 10
+	DefaultMaxSize = 64 << 10
+)
+
+// ErrConfig is returned for invalid chunker configurations.
+var ErrConfig = errors.New("cdc: invalid chunker configuration")
+
+// Config configures a Chunker. The zero value uses the default sizes
+// and the all-zero salt.
+type Config struct {
+	// MinSize, AvgSize and MaxSize bound the chunk sizes and set the
+	// target average. AvgSize must be a power of two, and
+	// 64 <= MinSize <= AvgSize <= MaxSize must hold.
+	MinSize, AvgSize, MaxSize int
+
+	// Salt is the 16-byte BLAKE-256 salt the gear table is generated
+	// from. If nil, the all-zero salt is used.
+	Salt []byte
+}
+
+// Chunk is a chunk of a stream.
+type Chunk struct {
+	Offset int64                // position of the chunk in the stream
+	Data   []byte               // contents, valid until the next call to Next
+	ID     blake.Digest256Value // BLAKE-256 checksum of Data
+}
+
+// Chunker splits a stream into chunks.
+type Chunker struct {
+	r             io.Reader
+	gear          [256]uint64
+	min, avg, max int
+	maskS, maskL  uint64
+
+	buf    []byte
+	start  int // start of unconsumed data in buf
+	end    int // end of valid data in buf
+	offset int64
+	eof    bool
+	err    error
+}
+
+// NewChunker returns a chunker reading from r.
+func NewChunker(r io.Reader, cfg *Config) (*Chunker, error) {
+	var c Config
+	if cfg != nil {
+		c = *cfg
+	}
+	if c.MinSize == 0 && c.AvgSize == 0 && c.MaxSize == 0 {
+		c.MinSize, c.AvgSize, c.MaxSize = DefaultMinSize, DefaultAvgSize, DefaultMaxSize
+	}
+	if c.MinSize < 64 || c.MinSize > c.AvgSize || c.AvgSize > c.MaxSize ||
+		c.AvgSize&(c.AvgSize-1) != 0 || (c.Salt != nil && len(c.Salt) != 16) {
+		return nil, ErrConfig
+	}
+	ch := &Chunker{
+		r:   r,
+		min: c.MinSize,
+		avg: c.AvgSize,
+		max: c.MaxSize,
+		buf: make([]byte, c.MaxSize),
+	}
+	ch.gear = gearTable(c.Salt)
+	// Normalized chunking: a stricter mask before the average size
+	// and a looser one after it pull chunk sizes towards the average.
+	n := bits.TrailingZeros(uint(c.AvgSize))
+	ch.maskS = topBits(n + 2)
+	ch.maskL = topBits(n - 2)
+	return ch, nil
+}
+
+// topBits returns a mask of the n most significant bits. The gear hash
+// shifts left, so its top bits depend on the most bytes.
+func topBits(n int) uint64 {
+	if n <= 0 {
+		return 0
+	}
+	return ^uint64(0) << (64 - n)
+}
+
+// gearTable fills the gear table with the BLAKE-256 checksums of the
+// counters 0 to 63, salted with salt.
+func gearTable(salt []byte) [256]uint64 {
+	var t [256]uint64
+	var ctr [4]byte
+	for i := 0; i < 256; i += 4 {
+		binary.BigEndian.PutUint32(ctr[:], uint32(i/4))
+		sum := blake.Sum256withSalt(ctr[:], salt)
+		for j := 0; j < 4; j++ {
+			t[i+j] = binary.BigEndian.Uint64(sum[8*j:])
+		}
+	}
+	return t
+}
+
+// cut returns the length of the chunk at the start of data.
+func (ch *Chunker) cut(data []byte) int {
+	n := len(data)
+	if n <= ch.min {
+		return n
+	}
+	if n > ch.max {
+		n = ch.max
+	}
+	normal := ch.avg
+	if n < normal {
+		normal = n
+	}
+	var fp uint64
+	i := ch.min
+	for ; i < normal; i++ {
+		fp = fp<<1 + ch.gear[data[i]]
+		if fp&ch.maskS == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		fp = fp<<1 + ch.gear[data[i]]
+		if fp&ch.maskL == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
+
+// fill reads until the buffer holds at least MaxSize bytes or the end
+// of the stream is reached.
+func (ch *Chunker) fill() error {
+	if ch.start > 0 {
+		ch.end = copy(ch.buf, ch.buf[ch.start:ch.end])
+		ch.start = 0
+	}
+	for !ch.eof && ch.end < ch.max {
+		n, err := ch.r.Read(ch.buf[ch.end:])
+		ch.end += n
+		if err == io.EOF {
+			ch.eof = true
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Next returns the next chunk, or io.EOF at the end of the stream.
+func (ch *Chunker) Next() (Chunk, error) {
+	if ch.err != nil {
+		return Chunk{}, ch.err
+	}
+	if ch.end-ch.start < ch.max {
+		if err := ch.fill(); err != nil {
+			ch.err = err
+			return Chunk{}, err
+		}
+	}
+	if ch.start == ch.end {
+		ch.err = io.EOF
+		return Chunk{}, io.EOF
+	}
+	data := ch.buf[ch.start:ch.end]
+	data = data[:ch.cut(data)]
+	c := Chunk{Offset: ch.offset, Data: data, ID: blake.Sum256(data)}
+	ch.start += len(data)
+	ch.offset += int64(len(data))
+	return c, nil
+}
@@ -0,0 +1,123 @@
+package cdc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+	"testing/iotest"
+
+	"github.com/ouzklcn/blake"
+)
+
+func random(n int, seed int64) []byte {
+	b := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(b)
+	return b
+}
+
+func split(t *testing.T, r io.Reader, cfg *Config) []Chunk {
+	t.Helper()
+	ch, err := NewChunker(r, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chunks []Chunk
+	for {
+		c, err := ch.Next()
+		if err == io.EOF {
+			return chunks
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Data = append([]byte(nil), c.Data...)
+		chunks = append(chunks, c)
+	}
+}
+
+func TestChunker(t *testing.T) {
+	data := random(1<<20, 1)
+	chunks := split(t, bytes.NewReader(data), nil)
+	var off int64
+	for i, c := range chunks {
+		if c.Offset != off {
+			t.Fatalf("%d: expected offset %d, got %d", i, off, c.Offset)
+		}
+		if !bytes.Equal(c.Data, data[off:off+int64(len(c.Data))]) {
+			t.Fatalf("%d: data does not match the stream", i)
+		}
+		if c.ID != blake.Sum256(c.Data) {
+			t.Errorf("%d: ID is not the BLAKE-256 checksum", i)
+		}
+		if (len(c.Data) < DefaultMinSize && i != len(chunks)-1) || len(c.Data) > DefaultMaxSize {
+			t.Errorf("%d: chunk size %d out of bounds", i, len(c.Data))
+		}
+		off += int64(len(c.Data))
+	}
+	if off != int64(len(data)) {
+		t.Errorf("chunks cover %d bytes, want %d", off, len(data))
+	}
+	if avg := len(data) / len(chunks); avg < DefaultAvgSize/2 || avg > 2*DefaultAvgSize {
+		t.Errorf("average chunk size %d far from %d", avg, DefaultAvgSize)
+	}
+
+	again := split(t, iotest.OneByteReader(bytes.NewReader(data)), nil)
+	if len(again) != len(chunks) {
+		t.Fatalf("short reads change the chunking: %d vs %d chunks", len(again), len(chunks))
+	}
+	for i := range again {
+		if again[i].ID != chunks[i].ID {
+			t.Errorf("%d: short reads change the chunk", i)
+		}
+	}
+
+	if got := split(t, bytes.NewReader(nil), nil); len(got) != 0 {
+		t.Errorf("empty stream produced %d chunks", len(got))
+	}
+}
+
+func TestSalt(t *testing.T) {
+	data := random(256<<10, 2)
+	cfg := &Config{MinSize: 1 << 10, AvgSize: 4 << 10, MaxSize: 16 << 10}
+	plain := split(t, bytes.NewReader(data), cfg)
+	cfg.Salt = blake.DeriveSalt256("backup")
+	salted := split(t, bytes.NewReader(data), cfg)
+	same := 0
+	for _, a := range plain {
+		for _, b := range salted {
+			if a.Offset == b.Offset && a.ID == b.ID {
+				same++
+			}
+		}
+	}
+	if same > len(plain)/4 {
+		t.Errorf("%d of %d chunks unchanged by the salt", same, len(plain))
+	}
+}
+
+func TestConfig(t *testing.T) {
+	for i, cfg := range []Config{
+		{MinSize: 32, AvgSize: 64, MaxSize: 128},
+		{MinSize: 2048, AvgSize: 1024, MaxSize: 4096},
+		{MinSize: 1024, AvgSize: 3000, MaxSize: 8192},
+		{MinSize: 1024, AvgSize: 4096, MaxSize: 2048},
+		{Salt: make([]byte, 8)},
+	} {
+		if _, err := NewChunker(nil, &cfg); err != ErrConfig {
+			t.Errorf("%d: expected ErrConfig, got %v", i, err)
+		}
+	}
+}
+
+func TestReadError(t *testing.T) {
+	errBroken := errors.New("broken")
+	ch, _ := NewChunker(iotest.ErrReader(errBroken), nil)
+	if _, err := ch.Next(); err != errBroken {
+		t.Errorf("expected read error, got %v", err)
+	}
+	if _, err := ch.Next(); err != errBroken {
+		t.Errorf("error is not sticky: %v", err)
+	}
+}
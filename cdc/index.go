@@ -0,0 +1,76 @@
+package cdc
+
+import (
+	"sync"
+
+	"github.com/ouzklcn/blake"
+)
+
+// Stats counts the chunks and bytes added to an Index.
+type Stats struct {
+	NewChunks    int   // chunks not seen before
+	NewBytes     int64 // bytes in new chunks
+	StoredChunks int   // chunks which were already stored
+	StoredBytes  int64 // bytes in chunks which were already stored
+}
+
+// Ratio returns the fraction of all added bytes which were already
+// stored, or 0 if nothing has been added.
+func (s Stats) Ratio() float64 {
+	total := s.NewBytes + s.StoredBytes
+	if total == 0 {
+		return 0
+	}
+	return float64(s.StoredBytes) / float64(total)
+}
+
+// Index records the IDs of stored chunks. It is safe for concurrent
+// use.
+type Index struct {
+	mu     sync.Mutex
+	chunks map[blake.Digest256Value]struct{}
+	stats  Stats
+}
+
+// NewIndex returns an empty index.
+func NewIndex() *Index {
+	return &Index{chunks: make(map[blake.Digest256Value]struct{})}
+}
+
+// Add records c and reports whether it is new, in which case its data
+// needs to be stored.
+func (x *Index) Add(c Chunk) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if _, ok := x.chunks[c.ID]; ok {
+		x.stats.StoredChunks++
+		x.stats.StoredBytes += int64(len(c.Data))
+		return false
+	}
+	x.chunks[c.ID] = struct{}{}
+	x.stats.NewChunks++
+	x.stats.NewBytes += int64(len(c.Data))
+	return true
+}
+
+// Has reports whether a chunk with the given ID has been added.
+func (x *Index) Has(id blake.Digest256Value) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	_, ok := x.chunks[id]
+	return ok
+}
+
+// Len returns the number of distinct chunks in the index.
+func (x *Index) Len() int {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return len(x.chunks)
+}
+
+// Stats returns the counts of the chunks added so far.
+func (x *Index) Stats() Stats {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.stats
+}
@@ -0,0 +1,44 @@
+package cdc
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestIndexDedup(t *testing.T) {
+	data := random(512<<10, 3)
+	// Insert bytes near the start; only the chunks around the edit
+	// should change.
+	edited := append(append(append([]byte(nil), data[:5000]...), "BLAKE"...), data[5000:]...)
+
+	x := NewIndex()
+	for _, c := range split(t, bytes.NewReader(data), nil) {
+		if !x.Add(c) {
+			t.Errorf("chunk at %d of the original reported as stored", c.Offset)
+		}
+	}
+	first := x.Stats()
+	if first.NewBytes != int64(len(data)) || first.StoredBytes != 0 {
+		t.Errorf("unexpected stats %+v", first)
+	}
+
+	for _, c := range split(t, bytes.NewReader(edited), nil) {
+		x.Add(c)
+	}
+	st := x.Stats()
+	if got := st.NewBytes - first.NewBytes + st.StoredBytes; got != int64(len(edited)) {
+		t.Errorf("stats cover %d bytes, want %d", got, len(edited))
+	}
+	if st.NewBytes-first.NewBytes > 3*DefaultMaxSize {
+		t.Errorf("edit of 5 bytes produced %d new bytes", st.NewBytes-first.NewBytes)
+	}
+	if st.Ratio() < 0.4 {
+		t.Errorf("dedup ratio %.2f too low", st.Ratio())
+	}
+	if x.Len() != st.NewChunks {
+		t.Errorf("Len() = %d, want %d", x.Len(), st.NewChunks)
+	}
+	if (Stats{}).Ratio() != 0 {
+		t.Error("empty stats have non-zero ratio")
+	}
+}
@@ -0,0 +1,167 @@
+// Package dirhash computes a single BLAKE-256 digest of a directory
+// tree, in the spirit of the h1: hashes of golang.org/x/mod/sumdb/dirhash.
+//
+// Walk builds a Manifest with one entry per file, sorted by path. Each
+// entry is recorded as the line
+//
+//	<hex BLAKE-256> <mode> <path>
+//
+// where the digest is that of the file contents, or of the target for
+// a symbolic link, and the mode is "f" for a file, "l" for a symbolic
+// link, or with Options.IncludeModes the four-digit octal permissions
+// of a file. The root digest is "b1:" followed by the base64 encoding of
+// the BLAKE-256 checksum of all lines.
+package dirhash
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/ouzklcn/blake"
+)
+
+// maxLinkDepth bounds the nesting of followed directory links, which
+// also stops symbolic link cycles.
+const maxLinkDepth = 16
+
+// Options configures Walk.
+type Options struct {
+	// FollowSymlinks hashes the files and directories symbolic links
+	// refer to instead of the link targets.
+	FollowSymlinks bool
+
+	// Ignore lists path.Match patterns. A file or directory is skipped
+	// if a pattern matches its slash-separated path or its base name.
+	Ignore []string
+
+	// IncludeModes records file permissions, so that for instance
+	// making a file executable changes the root digest.
+	IncludeModes bool
+}
+
+// ErrLinkDepth is returned when followed symbolic links nest too deeply.
+var ErrLinkDepth = errors.New("dirhash: too many levels of symbolic links")
+
+// Hash returns the root digest of the tree in fsys.
+func Hash(fsys fs.FS, opts *Options) (string, error) {
+	m, err := Walk(fsys, opts)
+	if err != nil {
+		return "", err
+	}
+	return m.Root(), nil
+}
+
+// Walk hashes every file in fsys and returns the manifest of the tree.
+// Files other than regular files and symbolic links are skipped.
+func Walk(fsys fs.FS, opts *Options) (Manifest, error) {
+	w := &walker{fsys: fsys}
+	if opts != nil {
+		w.opts = *opts
+	}
+	for _, p := range w.opts.Ignore {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.walk(".", 0); err != nil {
+		return nil, err
+	}
+	sort.Slice(w.m, func(i, j int) bool { return w.m[i].Path < w.m[j].Path })
+	return w.m, nil
+}
+
+type walker struct {
+	fsys fs.FS
+	opts Options
+	m    Manifest
+}
+
+func (w *walker) ignored(name string) bool {
+	for _, p := range w.opts.Ignore {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, path.Base(name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *walker) walk(dir string, depth int) error {
+	entries, err := fs.ReadDir(w.fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name := path.Join(dir, e.Name())
+		if w.ignored(name) {
+			continue
+		}
+		if strings.ContainsAny(name, "\n\r") {
+			return fmt.Errorf("dirhash: file name %q contains a newline", name)
+		}
+		switch {
+		case e.Type()&fs.ModeSymlink != 0 && !w.opts.FollowSymlinks:
+			target, err := fs.ReadLink(w.fsys, name)
+			if err != nil {
+				return err
+			}
+			w.m = append(w.m, Entry{name, "l", blake.Sum256([]byte(target))})
+		case e.Type()&fs.ModeSymlink != 0:
+			fi, err := fs.Stat(w.fsys, name)
+			if err != nil {
+				return err
+			}
+			if fi.IsDir() {
+				if depth >= maxLinkDepth {
+					return ErrLinkDepth
+				}
+				if err := w.walk(name, depth+1); err != nil {
+					return err
+				}
+			} else if fi.Mode().IsRegular() {
+				if err := w.addFile(name, fi.Mode()); err != nil {
+					return err
+				}
+			}
+		case e.IsDir():
+			if err := w.walk(name, depth); err != nil {
+				return err
+			}
+		case e.Type().IsRegular():
+			fi, err := e.Info()
+			if err != nil {
+				return err
+			}
+			if err := w.addFile(name, fi.Mode()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *walker) addFile(name string, mode fs.FileMode) error {
+	f, err := w.fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := blake.New256()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	e := Entry{Path: name, Mode: "f"}
+	if w.opts.IncludeModes {
+		e.Mode = fmt.Sprintf("%04o", mode.Perm())
+	}
+	h.Sum(e.Digest[:0])
+	w.m = append(w.m, e)
+	return nil
+}
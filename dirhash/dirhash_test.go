@@ -0,0 +1,108 @@
+package dirhash
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ouzklcn/blake"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"README":        {Data: []byte("BLAKE"), Mode: 0o644},
+		"bin/run":       {Data: []byte("#!/bin/sh\n"), Mode: 0o755},
+		"src/a.go":      {Data: []byte("package a\n"), Mode: 0o644},
+		"src/b_test.go": {Data: []byte("package a\n"), Mode: 0o644},
+		"link":          {Data: []byte("src/a.go"), Mode: fs.ModeSymlink | 0o777},
+	}
+}
+
+func TestWalk(t *testing.T) {
+	m, err := Walk(testFS(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"README", "bin/run", "link", "src/a.go", "src/b_test.go"}
+	if len(m) != len(want) {
+		t.Fatalf("expected %d entries, got %d:\n%s", len(want), len(m), m)
+	}
+	for i, e := range m {
+		if e.Path != want[i] {
+			t.Errorf("%d: expected %s, got %s", i, want[i], e.Path)
+		}
+	}
+	if m[0].Mode != "f" || m[0].Digest != blake.Sum256([]byte("BLAKE")) {
+		t.Errorf("unexpected entry %+v", m[0])
+	}
+	if m[2].Mode != "l" || m[2].Digest != blake.Sum256([]byte("src/a.go")) {
+		t.Errorf("symbolic link not recorded by its target: %+v", m[2])
+	}
+	line := blake.Digest256Value(blake.Sum256([]byte("BLAKE"))).String() + " f README\n"
+	if got := m.String()[:len(line)]; got != line {
+		t.Errorf("expected line %q, got %q", line, got)
+	}
+}
+
+func TestHashOptions(t *testing.T) {
+	fsys := testFS()
+	root, err := Hash(fsys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := Hash(fsys, nil); again != root {
+		t.Error("root digest is not deterministic")
+	}
+	if root[:3] != "b1:" {
+		t.Errorf("unexpected root %s", root)
+	}
+
+	fsys["bin/run"].Mode = 0o644
+	if got, _ := Hash(fsys, nil); got != root {
+		t.Error("mode changes the root without IncludeModes")
+	}
+	withModes, _ := Hash(fsys, &Options{IncludeModes: true})
+	fsys["bin/run"].Mode = 0o755
+	if got, _ := Hash(fsys, &Options{IncludeModes: true}); got == withModes {
+		t.Error("mode does not change the root with IncludeModes")
+	}
+
+	m, err := Walk(fsys, &Options{Ignore: []string{"*_test.go", "bin"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range m {
+		if e.Path == "src/b_test.go" || e.Path == "bin/run" {
+			t.Errorf("ignored file %s in manifest", e.Path)
+		}
+	}
+	if _, err := Walk(fsys, &Options{Ignore: []string{"["}}); err == nil {
+		t.Error("expected error for a malformed pattern")
+	}
+
+	m, err = Walk(fsys, &Options{FollowSymlinks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m[2].Path != "link" || m[2].Mode != "f" || m[2].Digest != blake.Sum256([]byte("package a\n")) {
+		t.Errorf("symbolic link not followed: %+v", m[2])
+	}
+}
+
+func TestSymlinkCycle(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "d"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("..", filepath.Join(dir, "d", "up")); err != nil {
+		t.Skip("symbolic links not supported:", err)
+	}
+	if _, err := Walk(os.DirFS(dir), nil); err != nil {
+		t.Errorf("unfollowed cycle: %v", err)
+	}
+	if _, err := Walk(os.DirFS(dir), &Options{FollowSymlinks: true}); err != ErrLinkDepth {
+		t.Errorf("expected ErrLinkDepth, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package dirhash
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/ouzklcn/blake"
+)
+
+// ErrManifest is returned when parsing malformed manifests.
+var ErrManifest = errors.New("dirhash: invalid manifest")
+
+// Entry is a file in a manifest.
+type Entry struct {
+	Path   string               // slash-separated path relative to the root
+	Mode   string               // "f", "l" or octal permissions
+	Digest blake.Digest256Value // checksum of the contents or link target
+}
+
+// Manifest lists the files of a tree sorted by path.
+type Manifest []Entry
+
+// String returns the lines of m, which are hashed to form the root.
+func (m Manifest) String() string {
+	var b strings.Builder
+	for _, e := range m {
+		b.WriteString(e.Digest.String())
+		b.WriteByte(' ')
+		b.WriteString(e.Mode)
+		b.WriteByte(' ')
+		b.WriteString(e.Path)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Root returns the root digest of m.
+func (m Manifest) Root() string {
+	sum := blake.Sum256([]byte(m.String()))
+	return "b1:" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// ParseManifest reads a manifest in the format of Manifest.String.
+// Entries must be sorted by path without duplicates.
+func ParseManifest(r io.Reader) (Manifest, error) {
+	var m Manifest
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		f := strings.SplitN(s.Text(), " ", 3)
+		if len(f) != 3 || f[1] == "" || f[2] == "" {
+			return nil, ErrManifest
+		}
+		d, err := blake.ParseDigest256Hex(f[0])
+		if err != nil {
+			return nil, ErrManifest
+		}
+		if n := len(m); n > 0 && m[n-1].Path >= f[2] {
+			return nil, ErrManifest
+		}
+		m = append(m, Entry{f[2], f[1], d})
+	}
+	return m, s.Err()
+}
+
+// ChangeKind is the kind of a Change.
+type ChangeKind int
+
+// Kinds of changes.
+const (
+	Added ChangeKind = iota
+	Removed
+	Modified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	}
+	return "unknown"
+}
+
+// Change is a difference between two manifests. Old is the zero Entry
+// for added files and New for removed ones.
+type Change struct {
+	Kind     ChangeKind
+	Path     string
+	Old, New Entry
+}
+
+// Diff returns the changes from old to new, sorted by path. A file
+// whose contents, type or mode differ is reported as Modified.
+func Diff(old, new Manifest) []Change {
+	var changes []Change
+	i, j := 0, 0
+	for i < len(old) || j < len(new) {
+		switch {
+		case j == len(new) || i < len(old) && old[i].Path < new[j].Path:
+			changes = append(changes, Change{Kind: Removed, Path: old[i].Path, Old: old[i]})
+			i++
+		case i == len(old) || new[j].Path < old[i].Path:
+			changes = append(changes, Change{Kind: Added, Path: new[j].Path, New: new[j]})
+			j++
+		default:
+			if old[i] != new[j] {
+				changes = append(changes, Change{Modified, old[i].Path, old[i], new[j]})
+			}
+			i++
+			j++
+		}
+	}
+	return changes
+}
@@ -0,0 +1,64 @@
+package dirhash
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	m, err := Walk(testFS(), &Options{IncludeModes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := ParseManifest(strings.NewReader(m.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Root() != m.Root() || len(p) != len(m) {
+		t.Errorf("parsed manifest differs:\n%s", p)
+	}
+	lines := strings.SplitAfter(m.String(), "\n")
+	for i, bad := range []string{
+		"xyz f README\n",
+		lines[0][:65] + "f\n",
+		lines[1] + lines[0],
+		lines[0] + lines[0],
+	} {
+		if _, err := ParseManifest(strings.NewReader(bad)); err != ErrManifest {
+			t.Errorf("%d: expected ErrManifest, got %v", i, err)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	fsys := testFS()
+	old, _ := Walk(fsys, nil)
+	delete(fsys, "README")
+	fsys["src/a.go"].Data = []byte("package b\n")
+	fsys["src/c.go"] = fsys["src/b_test.go"]
+	cur, _ := Walk(fsys, nil)
+
+	changes := Diff(old, cur)
+	want := []struct {
+		kind ChangeKind
+		path string
+	}{
+		{Removed, "README"},
+		{Modified, "src/a.go"},
+		{Added, "src/c.go"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), changes)
+	}
+	for i, c := range changes {
+		if c.Kind != want[i].kind || c.Path != want[i].path {
+			t.Errorf("%d: expected %s %s, got %s %s", i, want[i].kind, want[i].path, c.Kind, c.Path)
+		}
+	}
+	if changes[1].Old.Digest == changes[1].New.Digest {
+		t.Error("modified entry has the same digest")
+	}
+	if len(Diff(cur, cur)) != 0 {
+		t.Error("manifest differs from itself")
+	}
+}
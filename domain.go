@@ -0,0 +1,84 @@
+package blake
+
+import (
+	"hash"
+)
+
+// longContextTag marks salts derived from contexts too long to be
+// stored in the salt directly. It is larger than any direct length.
+const longContextTag = 0xff
+
+// deriveSalt fills salt, which is 16 or 32 bytes long, from context.
+//
+// A context of up to len(salt)-1 bytes is stored directly as
+//
+//	byte(len(context)) || context || zero padding
+//
+// which is injective. Longer contexts are stored as
+//
+//	0xff || BLAKE-512("blake domain\x00" || context)[:len(salt)-1]
+//
+// The tag byte keeps the two forms apart, so long contexts can only
+// collide with each other, and only through a collision of the
+// truncated BLAKE-512 hash.
+func deriveSalt(salt []byte, context string) {
+	if len(context) < len(salt) {
+		salt[0] = byte(len(context))
+		copy(salt[1:], context)
+		return
+	}
+	sum := Sum512(append([]byte("blake domain\x00"), context...))
+	salt[0] = longContextTag
+	copy(salt[1:], sum[:])
+}
+
+// DeriveSalt256 returns the 16-byte salt for BLAKE-224 and BLAKE-256
+// derived from a human-readable context string such as
+// "myapp 2026 session ids". Contexts of up to 15 bytes map to distinct
+// salts; see NewDomain256 for longer ones.
+// The empty context maps to the all-zero salt of the unsalted hash.
+func DeriveSalt256(context string) []byte {
+	salt := make([]byte, 16)
+	deriveSalt(salt, context)
+	return salt
+}
+
+// DeriveSalt512 returns the 32-byte salt for BLAKE-384 and BLAKE-512
+// derived from a context string. Contexts of up to 31 bytes map to
+// distinct salts.
+func DeriveSalt512(context string) []byte {
+	salt := make([]byte, 32)
+	deriveSalt(salt, context)
+	return salt
+}
+
+// NewDomain224 returns a new hash.Hash computing the BLAKE-224 checksum
+// salted with DeriveSalt256(context).
+func NewDomain224(context string) hash.Hash {
+	return New224withSalt(DeriveSalt256(context))
+}
+
+// NewDomain256 returns a new hash.Hash computing the BLAKE-256 checksum
+// salted with DeriveSalt256(context).
+//
+// Distinct contexts of up to 15 bytes are guaranteed to use distinct
+// salts. Longer contexts are compressed with BLAKE-512 into 120 bits,
+// so two of them share a salt only if that truncated hash collides.
+func NewDomain256(context string) hash.Hash {
+	return New256withSalt(DeriveSalt256(context))
+}
+
+// NewDomain384 returns a new hash.Hash computing the BLAKE-384 checksum
+// salted with DeriveSalt512(context).
+func NewDomain384(context string) hash.Hash {
+	return New384withSalt(DeriveSalt512(context))
+}
+
+// NewDomain512 returns a new hash.Hash computing the BLAKE-512 checksum
+// salted with DeriveSalt512(context).
+//
+// Distinct contexts of up to 31 bytes are guaranteed to use distinct
+// salts. Longer contexts are compressed with BLAKE-512 into 248 bits.
+func NewDomain512(context string) hash.Hash {
+	return New512withSalt(DeriveSalt512(context))
+}
@@ -0,0 +1,78 @@
+package blake
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDeriveSaltInjective(t *testing.T) {
+	// Short contexts over an alphabet including the padding byte, and
+	// contexts around the direct and hashed length boundaries.
+	contexts := []string{""}
+	alphabet := []string{"\x00", "\x01", "a", "\xff"}
+	for i := 0; i < 4; i++ {
+		for _, c := range contexts {
+			if len(c) == i {
+				for _, a := range alphabet {
+					contexts = append(contexts, c+a)
+				}
+			}
+		}
+	}
+	for n := 12; n <= 34; n++ {
+		contexts = append(contexts,
+			strings.Repeat("x", n),
+			strings.Repeat("x", n-1)+"\x00",
+			"\xff"+strings.Repeat("x", n-1))
+	}
+	for _, derive := range []func(string) []byte{DeriveSalt256, DeriveSalt512} {
+		seen := make(map[string]string)
+		for _, c := range contexts {
+			salt := string(derive(c))
+			if prev, ok := seen[salt]; ok && prev != c {
+				t.Errorf("contexts %q and %q map to the same salt", prev, c)
+			}
+			seen[salt] = c
+		}
+	}
+}
+
+func TestDeriveSalt(t *testing.T) {
+	if s := DeriveSalt256(""); !bytes.Equal(s, make([]byte, 16)) {
+		t.Errorf("empty context salt is %x", s)
+	}
+	if s := DeriveSalt256("abc"); !bytes.Equal(s, []byte("\x03abc\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")) {
+		t.Errorf("unexpected salt %q", s)
+	}
+	if s := DeriveSalt256("myapp 2026 session ids"); len(s) != 16 || s[0] != longContextTag {
+		t.Errorf("unexpected salt %x for a long context", s)
+	}
+	if s := DeriveSalt512("myapp 2026 session ids"); len(s) != 32 || s[0] != 22 {
+		t.Errorf("unexpected salt %x for a direct context", s)
+	}
+}
+
+func TestNewDomain(t *testing.T) {
+	const ctx = "myapp 2026 session ids"
+	data := []byte("Golang")
+	sum256 := Sum256withSalt(data, DeriveSalt256(ctx))
+	sum512 := Sum512withSalt(data, DeriveSalt512(ctx))
+	h := NewDomain256(ctx)
+	h.Write(data)
+	if !bytes.Equal(h.Sum(nil), sum256[:]) {
+		t.Error("NewDomain256 differs from Sum256withSalt")
+	}
+	h = NewDomain512(ctx)
+	h.Write(data)
+	if !bytes.Equal(h.Sum(nil), sum512[:]) {
+		t.Error("NewDomain512 differs from Sum512withSalt")
+	}
+	if NewDomain224(ctx).Size() != Size224 || NewDomain384(ctx).Size() != Size384 {
+		t.Error("unexpected checksum sizes")
+	}
+	a, b := NewDomain256("a"), NewDomain256("b")
+	if bytes.Equal(a.Sum(nil), b.Sum(nil)) {
+		t.Error("different contexts produce the same checksum")
+	}
+}
@@ -0,0 +1,178 @@
+// Package etag generates strong HTTP entity tags from BLAKE-256 digests
+// of response bodies and answers conditional requests with them.
+//
+// Handler buffers the responses of any handler to compute their tags.
+// FileServer serves static files with tags computed once per file and
+// cached by modification time and size.
+package etag
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/ouzklcn/blake"
+)
+
+// Sum returns the strong entity tag of a body, the quoted unpadded
+// base64url encoding of its BLAKE-256 checksum.
+func Sum(body []byte) string {
+	sum := blake.Sum256(body)
+	return format(sum[:])
+}
+
+func format(sum []byte) string {
+	return `"` + base64.RawURLEncoding.EncodeToString(sum) + `"`
+}
+
+// Handler returns a handler which adds a strong ETag to successful GET
+// and HEAD responses of next, unless next sets one itself, and answers
+// conditional requests:
+//
+//   - If-Match fails with 412 Precondition Failed unless one of the
+//     listed tags strongly matches the current one.
+//   - If-None-Match fails with 304 Not Modified for GET and HEAD and
+//     with 412 Precondition Failed for other methods if one of the
+//     listed tags weakly matches the current one.
+//
+// Responses to GET and HEAD are buffered, and HEAD requests are passed
+// to next as GET so that the tag covers the body. For other methods
+// with preconditions, the current tag is found by first passing a GET
+// request for the same URL to next, and next only handles the request
+// itself if the preconditions hold.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			if r.Header.Get("If-Match") != "" || r.Header.Get("If-None-Match") != "" {
+				if status := checkPreconditions(r, currentETag(next, r)); status != 0 {
+					w.WriteHeader(status)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := record(next, asGet(r))
+		etag := rec.header.Get("ETag")
+		if etag == "" && rec.status == http.StatusOK {
+			etag = Sum(rec.body.Bytes())
+			rec.header.Set("ETag", etag)
+		}
+		h := w.Header()
+		for k, v := range rec.header {
+			h[k] = v
+		}
+		if rec.status == http.StatusOK {
+			if status := checkPreconditions(r, etag); status != 0 {
+				if status == http.StatusNotModified {
+					h.Del("Content-Type")
+					h.Del("Content-Length")
+				}
+				w.WriteHeader(status)
+				return
+			}
+		}
+		w.WriteHeader(rec.status)
+		if r.Method != http.MethodHead {
+			w.Write(rec.body.Bytes())
+		}
+	})
+}
+
+// asGet returns r as a GET request without preconditions.
+func asGet(r *http.Request) *http.Request {
+	g := r.Clone(r.Context())
+	g.Method = http.MethodGet
+	g.Body = http.NoBody
+	g.ContentLength = 0
+	g.Header.Del("If-Match")
+	g.Header.Del("If-None-Match")
+	return g
+}
+
+// currentETag returns the tag of the representation a GET request for
+// the URL of r would return, or "" if there is none.
+func currentETag(next http.Handler, r *http.Request) string {
+	rec := record(next, asGet(r))
+	if rec.status != http.StatusOK {
+		return ""
+	}
+	if etag := rec.header.Get("ETag"); etag != "" {
+		return etag
+	}
+	return Sum(rec.body.Bytes())
+}
+
+// recorder is a buffering http.ResponseWriter.
+type recorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func record(h http.Handler, r *http.Request) *recorder {
+	rec := &recorder{header: make(http.Header), status: http.StatusOK}
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func (rec *recorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.wroteHeader = true
+		rec.status = status
+	}
+}
+
+func (rec *recorder) Write(p []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	return rec.body.Write(p)
+}
+
+// checkPreconditions evaluates If-Match and If-None-Match against the
+// current tag, which is empty if there is no current representation.
+// It returns the status to respond with, or 0 if the request should
+// proceed.
+func checkPreconditions(r *http.Request, etag string) int {
+	if im := r.Header.Get("If-Match"); im != "" {
+		if !matchList(im, etag, true) {
+			return http.StatusPreconditionFailed
+		}
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if matchList(inm, etag, false) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	}
+	return 0
+}
+
+// matchList reports whether the current tag matches a field value
+// listing entity tags or "*".
+func matchList(list, etag string, strong bool) bool {
+	if etag == "" {
+		return false
+	}
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+	for _, t := range strings.Split(list, ",") {
+		t = strings.TrimSpace(t)
+		if strong && (strings.HasPrefix(t, "W/") || strings.HasPrefix(etag, "W/")) {
+			continue
+		}
+		if strings.TrimPrefix(t, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package etag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serve(h http.Handler, method, inm, im string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/doc", nil)
+	if inm != "" {
+		r.Header.Set("If-None-Match", inm)
+	}
+	if im != "" {
+		r.Header.Set("If-Match", im)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestSum(t *testing.T) {
+	want := `"B2Y-AM-W-8E2z3se4JnJU0a6OSCJPRjMiFHyLuLjaqY"`
+	if got := Sum([]byte("BLAKE")); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	doc := "BLAKE"
+	var puts int
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(doc))
+		case http.MethodPut:
+			puts++
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	etag := Sum([]byte(doc))
+	other := Sum([]byte("blake"))
+	tests := []struct {
+		method, inm, im string
+		status          int
+	}{
+		{"GET", "", "", http.StatusOK},
+		{"GET", etag, "", http.StatusNotModified},
+		{"GET", other + ", W/" + etag, "", http.StatusNotModified},
+		{"GET", "*", "", http.StatusNotModified},
+		{"GET", other, "", http.StatusOK},
+		{"HEAD", etag, "", http.StatusNotModified},
+		{"GET", "", etag, http.StatusOK},
+		{"GET", "", "W/" + etag, http.StatusPreconditionFailed},
+		{"GET", "", other, http.StatusPreconditionFailed},
+		{"PUT", "", other, http.StatusPreconditionFailed},
+		{"PUT", "*", "", http.StatusPreconditionFailed},
+		{"PUT", "", etag, http.StatusNoContent},
+		{"PUT", "", "", http.StatusNoContent},
+	}
+	for i, tt := range tests {
+		w := serve(h, tt.method, tt.inm, tt.im)
+		if w.Code != tt.status {
+			t.Errorf("%d: expected status %d, got %d", i, tt.status, w.Code)
+		}
+		if tt.method == "GET" && w.Header().Get("ETag") != etag && tt.status != http.StatusPreconditionFailed {
+			t.Errorf("%d: expected ETag %s, got %q", i, etag, w.Header().Get("ETag"))
+		}
+		if tt.status == http.StatusNotModified && (w.Body.Len() != 0 || w.Header().Get("Content-Type") != "") {
+			t.Errorf("%d: 304 response has a body or Content-Type", i)
+		}
+	}
+	if puts != 2 {
+		t.Errorf("expected 2 PUTs to reach the handler, got %d", puts)
+	}
+
+	w := serve(h, "HEAD", "", "")
+	if w.Code != http.StatusOK || w.Body.Len() != 0 || w.Header().Get("ETag") != etag {
+		t.Errorf("unexpected HEAD response %d %q %q", w.Code, w.Body, w.Header().Get("ETag"))
+	}
+}
+
+func TestHandlerKeepsETag(t *testing.T) {
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("BLAKE"))
+	}))
+	if w := serve(h, "GET", `"v1"`, ""); w.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for the handler's own ETag, got %d", w.Code)
+	}
+	notFound := Handler(http.NotFoundHandler())
+	if w := serve(notFound, "GET", "", ""); w.Code != http.StatusNotFound || w.Header().Get("ETag") != "" {
+		t.Errorf("unexpected response %d with ETag %q", w.Code, w.Header().Get("ETag"))
+	}
+}
@@ -0,0 +1,108 @@
+package etag
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ouzklcn/blake"
+)
+
+// Cache holds the entity tags of files keyed by name. An entry is
+// reused while the modification time and size of the file are
+// unchanged. It is safe for concurrent use.
+type Cache struct {
+	mu      sync.RWMutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	modTime time.Time
+	size    int64
+	etag    string
+}
+
+// NewCache returns an empty cache.
+func NewCache() *Cache {
+	return &Cache{entries: make(map[string]cacheEntry)}
+}
+
+// Len returns the number of cached tags.
+func (c *Cache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+// FileETag returns the strong entity tag of the named regular file,
+// which is Sum of its contents. If c is not nil the tag is looked up in
+// and stored in c.
+func FileETag(fsys fs.FS, name string, c *Cache) (string, error) {
+	fi, err := fs.Stat(fsys, name)
+	if err != nil {
+		return "", err
+	}
+	if !fi.Mode().IsRegular() {
+		return "", &fs.PathError{Op: "etag", Path: name, Err: fs.ErrInvalid}
+	}
+	if c != nil {
+		c.mu.RLock()
+		e, ok := c.entries[name]
+		c.mu.RUnlock()
+		if ok && e.modTime.Equal(fi.ModTime()) && e.size == fi.Size() {
+			return e.etag, nil
+		}
+	}
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := blake.New256()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	etag := format(h.Sum(nil))
+
+	if c != nil {
+		c.mu.Lock()
+		c.entries[name] = cacheEntry{fi.ModTime(), fi.Size(), etag}
+		c.mu.Unlock()
+	}
+	return etag, nil
+}
+
+// Warm computes the tags of all regular files in fsys ahead of the
+// first requests for them.
+func (c *Cache) Warm(fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		_, err = FileETag(fsys, name, c)
+		return err
+	})
+}
+
+// FileServer returns a handler like http.FileServer(http.FS(fsys))
+// which sets the ETag of regular files, so that conditional requests
+// are answered with 304 Not Modified or 412 Precondition Failed. Tags
+// are cached in c if it is not nil.
+func FileServer(fsys fs.FS, c *Cache) http.Handler {
+	files := http.FileServer(http.FS(fsys))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if name == "" {
+			name = "."
+		}
+		if etag, err := FileETag(fsys, name, c); err == nil {
+			w.Header().Set("ETag", etag)
+		}
+		files.ServeHTTP(w, r)
+	})
+}
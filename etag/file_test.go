@@ -0,0 +1,73 @@
+package etag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestFileServer(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":     {Data: []byte("BLAKE"), ModTime: time.Unix(1, 0)},
+		"dir/b.txt": {Data: []byte("Golang"), ModTime: time.Unix(1, 0)},
+	}
+	c := NewCache()
+	if err := c.Warm(fsys); err != nil {
+		t.Fatal(err)
+	}
+	if c.Len() != 2 {
+		t.Errorf("expected 2 cached tags, got %d", c.Len())
+	}
+	h := FileServer(fsys, c)
+
+	get := func(path, inm, im string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		if inm != "" {
+			r.Header.Set("If-None-Match", inm)
+		}
+		if im != "" {
+			r.Header.Set("If-Match", im)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	etag := Sum([]byte("BLAKE"))
+	if w := get("/a.txt", "", ""); w.Code != http.StatusOK || w.Header().Get("ETag") != etag || w.Body.String() != "BLAKE" {
+		t.Errorf("unexpected response %d %q %q", w.Code, w.Header().Get("ETag"), w.Body)
+	}
+	if w := get("/a.txt", etag, ""); w.Code != http.StatusNotModified {
+		t.Errorf("expected 304, got %d", w.Code)
+	}
+	if w := get("/dir/b.txt", "", etag); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected 412, got %d", w.Code)
+	}
+
+	// A stale cache entry is not used once the file changes.
+	fsys["a.txt"] = &fstest.MapFile{Data: []byte("blake!"), ModTime: time.Unix(2, 0)}
+	if w := get("/a.txt", etag, ""); w.Code != http.StatusOK || w.Header().Get("ETag") != Sum([]byte("blake!")) {
+		t.Errorf("changed file served with %d %q", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func TestFileETagCache(t *testing.T) {
+	fsys := fstest.MapFS{"f": {Data: []byte("ube"), ModTime: time.Unix(1, 0)}}
+	c := NewCache()
+	first, err := FileETag(fsys, "f", c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Same size and modification time: the cached tag is returned.
+	fsys["f"].Data = []byte("UBE")
+	if got, _ := FileETag(fsys, "f", c); got != first {
+		t.Errorf("expected cached tag %s, got %s", first, got)
+	}
+	if got, _ := FileETag(fsys, "f", nil); got != Sum([]byte("UBE")) {
+		t.Errorf("uncached tag %s does not match the contents", got)
+	}
+	if _, err := FileETag(fsys, ".", c); err == nil {
+		t.Error("expected error for a directory")
+	}
+}
@@ -0,0 +1,221 @@
+// Package hashchain implements a tamper-evident append-only log in
+// which every record is chained to its predecessor with BLAKE-256.
+//
+// A record is encoded as
+//
+//	uint64(len(entry)) || entry || hash
+//
+// with the length in big-endian order and
+//
+//	hash = BLAKE-256(prev || uint64(len(entry)) || entry)
+//
+// where prev is the hash of the previous record, or 32 zero bytes for
+// the first record. The hash of the last record is the head of the log.
+package hashchain
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/ouzklcn/blake"
+)
+
+// MaxEntrySize is the largest entry accepted by Append and Verify.
+const MaxEntrySize = 64 << 20
+
+// ErrEntryTooLarge is returned when an entry exceeds MaxEntrySize.
+var ErrEntryTooLarge = errors.New("hashchain: entry too large")
+
+// Checkpoint identifies the state of a log by its number of records
+// and head hash.
+type Checkpoint struct {
+	Size uint64
+	Head [blake.Size256]byte
+}
+
+// checkpointContext prefixes signed checkpoints so that signatures
+// cannot be replayed in another protocol.
+const checkpointContext = "blake hashchain checkpoint v1\n"
+
+// Message returns the canonical encoding of c which is signed by Sign.
+func (c Checkpoint) Message() []byte {
+	b := make([]byte, 0, len(checkpointContext)+8+blake.Size256)
+	b = append(b, checkpointContext...)
+	b = binary.BigEndian.AppendUint64(b, c.Size)
+	return append(b, c.Head[:]...)
+}
+
+// Sign returns an Ed25519 signature of the checkpoint.
+func (c Checkpoint) Sign(key ed25519.PrivateKey) []byte {
+	return ed25519.Sign(key, c.Message())
+}
+
+// Verify reports whether sig is a valid signature of the checkpoint
+// by key.
+func (c Checkpoint) Verify(key ed25519.PublicKey, sig []byte) bool {
+	return ed25519.Verify(key, c.Message(), sig)
+}
+
+// chain computes record hashes.
+type chain struct {
+	h   hash.Hash
+	buf [8]byte
+}
+
+func newChain() *chain {
+	return &chain{h: blake.New256()}
+}
+
+func (c *chain) next(prev *[blake.Size256]byte, entry []byte) (sum [blake.Size256]byte) {
+	c.h.Reset()
+	c.h.Write(prev[:])
+	binary.BigEndian.PutUint64(c.buf[:], uint64(len(entry)))
+	c.h.Write(c.buf[:])
+	c.h.Write(entry)
+	c.h.Sum(sum[:0])
+	return
+}
+
+// Writer appends records to a log.
+type Writer struct {
+	w     io.Writer
+	chain *chain
+	cp    Checkpoint
+}
+
+// NewWriter returns a Writer which starts a new log on w.
+func NewWriter(w io.Writer) *Writer {
+	return ResumeWriter(w, Checkpoint{})
+}
+
+// ResumeWriter returns a Writer which appends to an existing log on w
+// whose current state is cp, as returned by Verify.
+func ResumeWriter(w io.Writer, cp Checkpoint) *Writer {
+	return &Writer{w: w, chain: newChain(), cp: cp}
+}
+
+// Append writes entry as the next record and returns the new head.
+func (w *Writer) Append(entry []byte) ([blake.Size256]byte, error) {
+	if len(entry) > MaxEntrySize {
+		return w.cp.Head, ErrEntryTooLarge
+	}
+	sum := w.chain.next(&w.cp.Head, entry)
+	rec := make([]byte, 0, 8+len(entry)+blake.Size256)
+	rec = binary.BigEndian.AppendUint64(rec, uint64(len(entry)))
+	rec = append(rec, entry...)
+	rec = append(rec, sum[:]...)
+	if _, err := w.w.Write(rec); err != nil {
+		return w.cp.Head, err
+	}
+	w.cp.Head = sum
+	w.cp.Size++
+	return sum, nil
+}
+
+// Checkpoint returns the current state of the log.
+func (w *Writer) Checkpoint() Checkpoint { return w.cp }
+
+// CorruptionError reports the first record of a log which fails to
+// verify.
+type CorruptionError struct {
+	Index  uint64 // index of the record
+	Offset int64  // byte offset of the start of the record
+	Err    error
+}
+
+func (e *CorruptionError) Error() string {
+	return fmt.Sprintf("hashchain: record %d at offset %d: %v", e.Index, e.Offset, e.Err)
+}
+
+func (e *CorruptionError) Unwrap() error { return e.Err }
+
+// ErrHashMismatch is wrapped by a CorruptionError when the stored
+// hash of a record does not match its contents and predecessor.
+var ErrHashMismatch = errors.New("hash mismatch")
+
+// Verify reads a complete log from r and checks every record. It
+// returns the final checkpoint, or a *CorruptionError identifying the
+// first record which is truncated, too large or does not match its
+// hash. Errors reading r are returned as they are.
+func Verify(r io.Reader) (Checkpoint, error) {
+	return VerifyFrom(r, Checkpoint{}, nil)
+}
+
+// VerifyFrom is like Verify but continues a log whose state at the
+// start of r is cp. If fn is not nil, it is called with the index and
+// contents of every verified record; the entry is only valid for the
+// duration of the call.
+func VerifyFrom(r io.Reader, cp Checkpoint, fn func(index uint64, entry []byte) error) (Checkpoint, error) {
+	br := bufio.NewReader(r)
+	c := newChain()
+	var (
+		offset int64
+		hdr    [8]byte
+		stored [blake.Size256]byte
+		entry  []byte
+	)
+	fail := func(err error) (Checkpoint, error) {
+		return cp, &CorruptionError{Index: cp.Size, Offset: offset, Err: err}
+	}
+	for {
+		m, eof, err := readFull(br, hdr[:])
+		if err != nil {
+			return cp, err
+		}
+		if eof {
+			if m == 0 {
+				return cp, nil
+			}
+			return fail(io.ErrUnexpectedEOF)
+		}
+		n := binary.BigEndian.Uint64(hdr[:])
+		if n > MaxEntrySize {
+			return fail(ErrEntryTooLarge)
+		}
+		if uint64(cap(entry)) < n {
+			entry = make([]byte, n)
+		}
+		entry = entry[:n]
+		for _, b := range [][]byte{entry, stored[:]} {
+			if _, eof, err := readFull(br, b); err != nil {
+				return cp, err
+			} else if eof {
+				return fail(io.ErrUnexpectedEOF)
+			}
+		}
+		sum := c.next(&cp.Head, entry)
+		if sum != stored {
+			return fail(ErrHashMismatch)
+		}
+		if fn != nil {
+			if err := fn(cp.Size, entry); err != nil {
+				return cp, err
+			}
+		}
+		cp.Head = sum
+		cp.Size++
+		offset += int64(8 + len(entry) + blake.Size256)
+	}
+}
+
+// readFull fills b from r and reports whether r ended first, in which
+// case m is the number of bytes read. Errors from r, including
+// io.ErrUnexpectedEOF, are returned as they are.
+func readFull(r io.Reader, b []byte) (m int, eof bool, err error) {
+	for m < len(b) {
+		n, err := r.Read(b[m:])
+		m += n
+		if err == io.EOF {
+			return m, m < len(b), nil
+		}
+		if err != nil {
+			return m, false, err
+		}
+	}
+	return m, false, nil
+}
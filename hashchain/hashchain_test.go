@@ -0,0 +1,112 @@
+package hashchain
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/ouzklcn/blake"
+)
+
+func buildLog(t *testing.T, n int) (*bytes.Buffer, Checkpoint, []int64) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	var offsets []int64
+	for i := 0; i < n; i++ {
+		offsets = append(offsets, int64(buf.Len()))
+		if _, err := w.Append([]byte(fmt.Sprintf("entry %d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &buf, w.Checkpoint(), offsets
+}
+
+func TestChain(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	h1, _ := w.Append([]byte("ab"))
+	h2, _ := w.Append([]byte("c"))
+
+	var zero [blake.Size256]byte
+	want1 := blake.Sum256(append(append(zero[:], 0, 0, 0, 0, 0, 0, 0, 2), "ab"...))
+	want2 := blake.Sum256(append(append(want1[:], 0, 0, 0, 0, 0, 0, 0, 1), "c"...))
+	if h1 != want1 || h2 != want2 {
+		t.Errorf("unexpected heads %x, %x", h1, h2)
+	}
+	if cp := w.Checkpoint(); cp.Size != 2 || cp.Head != h2 {
+		t.Errorf("unexpected checkpoint %+v", cp)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	buf, want, _ := buildLog(t, 10)
+	var entries []string
+	cp, err := VerifyFrom(bytes.NewReader(buf.Bytes()), Checkpoint{}, func(i uint64, e []byte) error {
+		entries = append(entries, string(e))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp != want {
+		t.Errorf("expected checkpoint %+v, got %+v", want, cp)
+	}
+	if len(entries) != 10 || entries[9] != "entry 9" {
+		t.Errorf("unexpected entries %q", entries)
+	}
+
+	// Resuming continues the same chain.
+	w := ResumeWriter(buf, cp)
+	w.Append([]byte("entry 10"))
+	if cp, err := Verify(buf); err != nil || cp != w.Checkpoint() {
+		t.Errorf("resumed log: %+v, %v", cp, err)
+	}
+}
+
+func TestVerifyCorruption(t *testing.T) {
+	buf, _, offsets := buildLog(t, 10)
+	b := buf.Bytes()
+	b[offsets[6]+9] ^= 1
+	_, err := Verify(bytes.NewReader(b))
+	var ce *CorruptionError
+	if !errors.As(err, &ce) {
+		t.Fatalf("expected CorruptionError, got %v", err)
+	}
+	if ce.Index != 6 || ce.Offset != offsets[6] || !errors.Is(err, ErrHashMismatch) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	buf, _, offsets = buildLog(t, 3)
+	_, err = Verify(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	if !errors.As(err, &ce) || ce.Index != 2 || ce.Offset != offsets[2] || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("unexpected error for truncated log: %v", err)
+	}
+
+	// Read errors, even io.ErrUnexpectedEOF, are not corruption.
+	for _, rerr := range []error{errors.New("disk failure"), io.ErrUnexpectedEOF} {
+		r := io.MultiReader(bytes.NewReader(buf.Bytes()[:offsets[2]+3]), iotest.ErrReader(rerr))
+		if _, err := Verify(r); err != rerr {
+			t.Errorf("expected %v, got %v", rerr, err)
+		}
+	}
+}
+
+func TestCheckpointSignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, cp, _ := buildLog(t, 3)
+	sig := cp.Sign(priv)
+	if !cp.Verify(pub, sig) {
+		t.Error("valid checkpoint signature rejected")
+	}
+	cp.Size++
+	if cp.Verify(pub, sig) {
+		t.Error("signature verified for a different checkpoint")
+	}
+}
@@ -0,0 +1,99 @@
+package httpdigest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// DefaultWant is the Want-Content-Digest field value sent by Transport
+// when none is configured.
+const DefaultWant = "blake-512=10, blake-256=5"
+
+// SetContentDigest reads the body of req and sets its Content-Digest
+// field. The body is buffered and req.GetBody is set so that the request
+// can be retried.
+func SetContentDigest(req *http.Request, alg Algorithm) error {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return err
+		}
+		req.Body.Close()
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set(ContentDigest, Digests{alg: alg.Sum(body)}.String())
+	return nil
+}
+
+// Transport is an http.RoundTripper which asks servers for a
+// Content-Digest and verifies response bodies against it as they are
+// read. Reading the end of a body which does not match returns
+// ErrMismatch.
+//
+// When the underlying transport has transparently decompressed a
+// response, the Content-Digest of the compressed content cannot be
+// checked and the Repr-Digest is verified instead.
+type Transport struct {
+	// Base is the underlying transport. If nil, http.DefaultTransport
+	// is used.
+	Base http.RoundTripper
+
+	// Want is sent as Want-Content-Digest on requests which do not
+	// have one. If empty, DefaultWant is used.
+	Want string
+
+	// Require makes RoundTrip fail with ErrNoDigest for responses
+	// with a body but without a digest that can be verified.
+	Require bool
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get(WantContentDigest) == "" {
+		want := t.Want
+		if want == "" {
+			want = DefaultWant
+		}
+		req = req.Clone(req.Context())
+		req.Header.Set(WantContentDigest, want)
+	}
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Method == http.MethodHead || !bodyAllowed(resp.StatusCode) {
+		return resp, nil
+	}
+
+	name := ContentDigest
+	if resp.Uncompressed {
+		name = ReprDigest
+		if resp.StatusCode == http.StatusPartialContent {
+			name = ""
+		}
+	}
+	var d Digests
+	if name != "" {
+		if d, err = Parse(fieldValue(resp.Header, name)); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+	body, ok := newVerifier(resp.Body, d, nil)
+	if !ok && t.Require {
+		resp.Body.Close()
+		return nil, ErrNoDigest
+	}
+	resp.Body = body
+	return resp, nil
+}
@@ -0,0 +1,62 @@
+package httpdigest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(WantContentDigest) != DefaultWant {
+			t.Errorf("unexpected Want-Content-Digest %q", r.Header.Get(WantContentDigest))
+		}
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			return
+		}
+		w.Write(b)
+	}), nil))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &Transport{}}
+	req, _ := http.NewRequest("POST", srv.URL, strings.NewReader("Golang"))
+	if err := SetContentDigest(req, BLAKE512); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get(ContentDigest) == "" {
+		t.Error("response has no Content-Digest")
+	}
+	if b, err := io.ReadAll(resp.Body); err != nil || string(b) != "Golang" {
+		t.Errorf("unexpected body %q, %v", b, err)
+	}
+}
+
+func TestTransportMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ContentDigest, Digests{BLAKE256: BLAKE256.Sum([]byte("BLAKE"))}.String())
+		w.Write([]byte("blake"))
+	}))
+	defer srv.Close()
+	resp, err := (&http.Client{Transport: &Transport{}}).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if _, err := io.ReadAll(resp.Body); err != ErrMismatch {
+		t.Errorf("expected ErrMismatch, got %v", err)
+	}
+
+	plain := httptest.NewServer(echo)
+	defer plain.Close()
+	if _, err := (&http.Client{Transport: &Transport{Require: true}}).Get(plain.URL); err == nil || !strings.Contains(err.Error(), ErrNoDigest.Error()) {
+		t.Errorf("expected ErrNoDigest, got %v", err)
+	}
+}
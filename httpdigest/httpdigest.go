@@ -0,0 +1,312 @@
+// Package httpdigest computes and verifies the HTTP Content-Digest and
+// Repr-Digest fields of RFC 9530 with BLAKE-256 and BLAKE-512.
+//
+// The fields are structured field dictionaries mapping an algorithm to a
+// byte sequence:
+//
+//	Content-Digest: blake-512=:<base64>:, blake-256=:<base64>:
+//
+// and the Want-Content-Digest and Want-Repr-Digest fields map algorithms
+// to preferences from 0 to 10, where 0 means not acceptable:
+//
+//	Want-Content-Digest: blake-512=10, blake-256=3
+//
+// The algorithm keys are not in the IANA registry and are only
+// understood by peers using this package. Unknown algorithms in
+// received fields are ignored.
+//
+// Handler wraps a server and Transport wraps a client. Both verify
+// bodies as they are read and report a mismatch once the end of the
+// body is reached.
+package httpdigest
+
+import (
+	"encoding/base64"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ouzklcn/blake"
+)
+
+// Field names.
+const (
+	ContentDigest     = "Content-Digest"
+	ReprDigest        = "Repr-Digest"
+	WantContentDigest = "Want-Content-Digest"
+	WantReprDigest    = "Want-Repr-Digest"
+)
+
+// Algorithm is a digest algorithm key.
+type Algorithm string
+
+// Supported algorithms.
+const (
+	BLAKE256 Algorithm = "blake-256"
+	BLAKE512 Algorithm = "blake-512"
+)
+
+// algorithms lists the supported algorithms, strongest first.
+var algorithms = []struct {
+	alg Algorithm
+	new func() hash.Hash
+}{
+	{BLAKE512, blake.New512},
+	{BLAKE256, blake.New256},
+}
+
+var (
+	// ErrMismatch is returned when a body does not match its digest.
+	ErrMismatch = errors.New("httpdigest: digest mismatch")
+	// ErrNoDigest is returned when a digest is required but none with
+	// a supported algorithm is present.
+	ErrNoDigest = errors.New("httpdigest: no supported digest")
+	// ErrSyntax is returned for malformed fields.
+	ErrSyntax = errors.New("httpdigest: invalid structured field")
+)
+
+func newHash(alg Algorithm) hash.Hash {
+	for _, a := range algorithms {
+		if a.alg == alg {
+			return a.new()
+		}
+	}
+	return nil
+}
+
+// Supported reports whether alg is a supported algorithm.
+func (alg Algorithm) Supported() bool {
+	return newHash(alg) != nil
+}
+
+// Sum returns the digest of data. It panics if alg is not supported.
+func (alg Algorithm) Sum(data []byte) []byte {
+	h := newHash(alg)
+	if h == nil {
+		panic("httpdigest: unsupported algorithm " + string(alg))
+	}
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// Digests maps algorithms to digests.
+type Digests map[Algorithm][]byte
+
+// String formats d as a dictionary field value, strongest algorithm
+// first.
+func (d Digests) String() string {
+	var s []string
+	for _, a := range algorithms {
+		if v, ok := d[a.alg]; ok {
+			s = append(s, string(a.alg)+"=:"+base64.StdEncoding.EncodeToString(v)+":")
+		}
+	}
+	return strings.Join(s, ", ")
+}
+
+// strongest returns the strongest supported algorithm in d.
+func (d Digests) strongest() (Algorithm, bool) {
+	for _, a := range algorithms {
+		if _, ok := d[a.alg]; ok {
+			return a.alg, true
+		}
+	}
+	return "", false
+}
+
+// member is a dictionary member with its parameters removed.
+type member struct {
+	key, value string
+}
+
+// parseDictionary splits a structured field dictionary into members.
+// Values are returned unparsed; a member without a value is the
+// boolean true and has the value "?1".
+func parseDictionary(s string) ([]member, error) {
+	var ms []member
+	s = strings.TrimLeft(s, " \t")
+	for s != "" {
+		i := keyLen(s)
+		if i == 0 {
+			return nil, ErrSyntax
+		}
+		m := member{key: s[:i], value: "?1"}
+		s = s[i:]
+		if strings.HasPrefix(s, "=") {
+			n := valueLen(s[1:])
+			if n == 0 {
+				return nil, ErrSyntax
+			}
+			m.value, s = s[1:1+n], s[1+n:]
+		}
+		// Skip parameters.
+		for strings.HasPrefix(s, ";") {
+			s = strings.TrimLeft(s[1:], " ")
+			i := keyLen(s)
+			if i == 0 {
+				return nil, ErrSyntax
+			}
+			s = s[i:]
+			if strings.HasPrefix(s, "=") {
+				n := valueLen(s[1:])
+				if n == 0 {
+					return nil, ErrSyntax
+				}
+				s = s[1+n:]
+			}
+		}
+		ms = append(ms, m)
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			break
+		}
+		if s[0] != ',' {
+			return nil, ErrSyntax
+		}
+		s = strings.TrimLeft(s[1:], " \t")
+		if s == "" {
+			return nil, ErrSyntax
+		}
+	}
+	return ms, nil
+}
+
+// keyLen returns the length of the key at the start of s.
+func keyLen(s string) int {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', c == '*':
+		case i > 0 && ('0' <= c && c <= '9' || c == '_' || c == '-' || c == '.'):
+		default:
+			return i
+		}
+	}
+	return len(s)
+}
+
+// valueLen returns the length of the bare item at the start of s, or 0
+// if it is malformed.
+func valueLen(s string) int {
+	if s == "" {
+		return 0
+	}
+	switch s[0] {
+	case ':':
+		i := strings.IndexByte(s[1:], ':')
+		if i < 0 {
+			return 0
+		}
+		return i + 2
+	case '"':
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1
+			}
+		}
+		return 0
+	}
+	i := strings.IndexAny(s, ";, \t")
+	if i < 0 {
+		return len(s)
+	}
+	return i
+}
+
+// Parse parses a Content-Digest or Repr-Digest field value. Members with
+// unsupported algorithms are ignored.
+func Parse(field string) (Digests, error) {
+	ms, err := parseDictionary(field)
+	if err != nil {
+		return nil, err
+	}
+	d := make(Digests)
+	for _, m := range ms {
+		alg := Algorithm(m.key)
+		if !alg.Supported() {
+			continue
+		}
+		v := m.value
+		if len(v) < 2 || v[0] != ':' || v[len(v)-1] != ':' {
+			return nil, ErrSyntax
+		}
+		b, err := base64.StdEncoding.DecodeString(v[1 : len(v)-1])
+		if err != nil || len(b) != newHash(alg).Size() {
+			return nil, ErrSyntax
+		}
+		d[alg] = b
+	}
+	return d, nil
+}
+
+// Negotiate returns the supported algorithm with the highest non-zero
+// preference in a Want-Content-Digest or Want-Repr-Digest field value,
+// preferring stronger algorithms on ties. It returns false if the field
+// is empty, malformed or accepts no supported algorithm.
+func Negotiate(want string) (Algorithm, bool) {
+	ms, err := parseDictionary(want)
+	if err != nil {
+		return "", false
+	}
+	prefs := make(map[Algorithm]int)
+	for _, m := range ms {
+		p, err := strconv.Atoi(m.value)
+		if err != nil || p < 0 || p > 10 {
+			return "", false
+		}
+		prefs[Algorithm(m.key)] = p
+	}
+	var best Algorithm
+	bestPref := 0
+	for _, a := range algorithms {
+		if p := prefs[a.alg]; p > bestPref {
+			best, bestPref = a.alg, p
+		}
+	}
+	return best, bestPref > 0
+}
+
+// fieldValue joins all lines of a field.
+func fieldValue(h http.Header, name string) string {
+	return strings.Join(h.Values(name), ", ")
+}
+
+// verifier checks a body against a digest as it is read, reporting a
+// mismatch as ErrMismatch.
+type verifier struct {
+	*blake.VerifyingReader
+	body       io.Closer
+	onMismatch func()
+}
+
+// newVerifier wraps r to check it against the strongest digest in d.
+// It returns r unchanged if d has no supported digest.
+func newVerifier(r io.ReadCloser, d Digests, onMismatch func()) (io.ReadCloser, bool) {
+	alg, ok := d.strongest()
+	if !ok {
+		return r, false
+	}
+	return &verifier{blake.NewVerifyingReader(r, newHash(alg), d[alg]), r, onMismatch}, true
+}
+
+func (v *verifier) Read(p []byte) (int, error) {
+	n, err := v.VerifyingReader.Read(p)
+	if err == blake.ErrDigestMismatch {
+		err = ErrMismatch
+		if v.onMismatch != nil {
+			v.onMismatch()
+			v.onMismatch = nil
+		}
+	}
+	return n, err
+}
+
+func (v *verifier) Close() error {
+	return v.body.Close()
+}
@@ -0,0 +1,69 @@
+package httpdigest
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFormatParse(t *testing.T) {
+	d := Digests{BLAKE256: BLAKE256.Sum([]byte("BLAKE")), BLAKE512: BLAKE512.Sum([]byte("BLAKE"))}
+	s := d.String()
+	want := "blake-512=:" // strongest first
+	if s[:len(want)] != want {
+		t.Errorf("expected %s..., got %s", want, s)
+	}
+	p, err := Parse(s)
+	if err != nil || len(p) != 2 || !bytes.Equal(p[BLAKE256], d[BLAKE256]) || !bytes.Equal(p[BLAKE512], d[BLAKE512]) {
+		t.Errorf("Parse(%s) = %v, %v", s, p, err)
+	}
+	if got := (Digests{BLAKE256: BLAKE256.Sum([]byte("BLAKE"))}).String(); got != "blake-256=:B2Y+AM+W+8E2z3se4JnJU0a6OSCJPRjMiFHyLuLjaqY=:" {
+		t.Errorf("unexpected field value %s", got)
+	}
+}
+
+func TestParseDictionary(t *testing.T) {
+	b256 := Digests{BLAKE256: BLAKE256.Sum(nil)}.String()
+	tests := []struct {
+		in   string
+		n    int
+		fail bool
+	}{
+		{"", 0, false},
+		{"sha-256=:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=:", 0, false},
+		{`sha-512=:AAAA:;p="a,b", ` + b256, 1, false},
+		{b256 + ";q=1", 1, false},
+		{"blake-256=?1", 0, true},
+		{"blake-256=:AAAA:", 0, true},
+		{"blake-256=:abc", 0, true},
+		{b256 + ",", 0, true},
+		{"Blake-256=:AAAA:", 0, true},
+	}
+	for i, tt := range tests {
+		d, err := Parse(tt.in)
+		if (err != nil) != tt.fail || len(d) != tt.n {
+			t.Errorf("%d: Parse(%q) = %v, %v", i, tt.in, d, err)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		in  string
+		alg Algorithm
+		ok  bool
+	}{
+		{"", "", false},
+		{"sha-256=10", "", false},
+		{"blake-256=1, sha-256=10", BLAKE256, true},
+		{"blake-256=5, blake-512=3", BLAKE256, true},
+		{"blake-256=5, blake-512=5", BLAKE512, true},
+		{"blake-512=0, blake-256=2", BLAKE256, true},
+		{"blake-512=11", "", false},
+		{"blake-512", "", false},
+	}
+	for i, tt := range tests {
+		if alg, ok := Negotiate(tt.in); alg != tt.alg || ok != tt.ok {
+			t.Errorf("%d: Negotiate(%q) = %s, %v", i, tt.in, alg, ok)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package httpdigest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// Options configures Handler.
+type Options struct {
+	// RequireRequestDigest rejects requests which have a body but no
+	// Content-Digest or Repr-Digest with a supported algorithm.
+	RequireRequestDigest bool
+
+	// ResponseAlgorithm, if set, adds a Content-Digest with this
+	// algorithm to every response. Otherwise responses carry digests
+	// only when the client asks for them with Want-Content-Digest or
+	// Want-Repr-Digest.
+	ResponseAlgorithm Algorithm
+}
+
+// Handler returns a handler which verifies request bodies against their
+// Content-Digest, or Repr-Digest when the body has no content coding,
+// and adds digests to the responses of next.
+//
+// Request bodies are verified as next reads them. If a body does not
+// match, reading its end returns ErrMismatch and the client receives a
+// 400 Bad Request unless next has already started sending its response,
+// in which case further writes by next fail. Handlers which do not read
+// the whole body see no error.
+//
+// Responses with digests are buffered, because the fields must be sent
+// before the body. A Repr-Digest is only added to complete responses
+// without a content coding.
+func Handler(next http.Handler, opts *Options) http.Handler {
+	if opts == nil {
+		opts = &Options{}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseWriter{w: w, status: http.StatusOK}
+		verified := false
+
+		if name, d, err := requestDigests(r); err != nil {
+			http.Error(w, "invalid "+name+" field", http.StatusBadRequest)
+			return
+		} else if body, ok := newVerifier(r.Body, d, func() { rw.fail(name) }); ok {
+			r.Body = body
+			verified = true
+		} else if opts.RequireRequestDigest && r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0 {
+			http.Error(w, "missing Content-Digest field", http.StatusBadRequest)
+			return
+		}
+
+		if r.Method != http.MethodHead {
+			alg, ok := Negotiate(fieldValue(r.Header, WantContentDigest))
+			if !ok && opts.ResponseAlgorithm.Supported() {
+				alg, ok = opts.ResponseAlgorithm, true
+			}
+			if ok {
+				rw.contentAlg = alg
+			}
+			if alg, ok := Negotiate(fieldValue(r.Header, WantReprDigest)); ok {
+				rw.reprAlg = alg
+			}
+			if rw.contentAlg != "" || rw.reprAlg != "" {
+				rw.buf = new(bytes.Buffer)
+			}
+		}
+
+		if !verified && rw.buf == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(rw, r)
+		rw.finish()
+	})
+}
+
+// requestDigests returns the digests a request body is checked
+// against and the name of the field they came from.
+func requestDigests(r *http.Request) (string, Digests, error) {
+	if v := fieldValue(r.Header, ContentDigest); v != "" {
+		d, err := Parse(v)
+		return ContentDigest, d, err
+	}
+	if v := fieldValue(r.Header, ReprDigest); v != "" && r.Header.Get("Content-Encoding") == "" {
+		d, err := Parse(v)
+		return ReprDigest, d, err
+	}
+	return ContentDigest, nil, nil
+}
+
+// responseWriter buffers the response when digests are added to it and
+// replaces it with an error when the request body does not match.
+type responseWriter struct {
+	w           http.ResponseWriter
+	buf         *bytes.Buffer // nil when passing writes through
+	status      int
+	wroteHeader bool
+	failed      bool
+
+	contentAlg, reprAlg Algorithm
+}
+
+func (rw *responseWriter) Header() http.Header {
+	return rw.w.Header()
+}
+
+// Unwrap returns the underlying ResponseWriter, for use by
+// http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.w
+}
+
+func (rw *responseWriter) WriteHeader(status int) {
+	if rw.wroteHeader || rw.failed {
+		return
+	}
+	rw.wroteHeader = true
+	rw.status = status
+	if rw.buf == nil {
+		rw.w.WriteHeader(status)
+	}
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	if rw.failed {
+		return 0, ErrMismatch
+	}
+	rw.WriteHeader(http.StatusOK)
+	if rw.buf != nil {
+		return rw.buf.Write(p)
+	}
+	return rw.w.Write(p)
+}
+
+// fail replaces the response with a 400 Bad Request if nothing has
+// been sent yet.
+func (rw *responseWriter) fail(name string) {
+	sent := rw.wroteHeader && rw.buf == nil
+	rw.failed = true
+	if sent {
+		return
+	}
+	h := rw.w.Header()
+	for k := range h {
+		delete(h, k)
+	}
+	http.Error(rw.w, name+" does not match the request body", http.StatusBadRequest)
+}
+
+// finish sends a buffered response with its digests.
+func (rw *responseWriter) finish() {
+	if rw.failed || rw.buf == nil {
+		return
+	}
+	h := rw.w.Header()
+	if bodyAllowed(rw.status) {
+		body := rw.buf.Bytes()
+		if rw.contentAlg != "" {
+			h.Set(ContentDigest, Digests{rw.contentAlg: rw.contentAlg.Sum(body)}.String())
+		}
+		if rw.reprAlg != "" && rw.status != http.StatusPartialContent && h.Get("Content-Encoding") == "" {
+			h.Set(ReprDigest, Digests{rw.reprAlg: rw.reprAlg.Sum(body)}.String())
+		}
+	}
+	rw.w.WriteHeader(rw.status)
+	io.Copy(rw.w, rw.buf)
+}
+
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
@@ -0,0 +1,135 @@
+package httpdigest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var echo = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(b)
+})
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandlerRequest(t *testing.T) {
+	h := Handler(echo, &Options{RequireRequestDigest: true})
+	body := "Golang"
+	tests := []struct {
+		name, value string
+		status      int
+	}{
+		{ContentDigest, Digests{BLAKE256: BLAKE256.Sum([]byte(body))}.String(), http.StatusOK},
+		{ReprDigest, Digests{BLAKE512: BLAKE512.Sum([]byte(body))}.String(), http.StatusOK},
+		{ContentDigest, Digests{BLAKE256: BLAKE256.Sum([]byte("golang"))}.String(), http.StatusBadRequest},
+		{ContentDigest, "blake-256=:Zm9v:", http.StatusBadRequest},
+		{"", "", http.StatusBadRequest},
+	}
+	for i, tt := range tests {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		if tt.name != "" {
+			r.Header.Set(tt.name, tt.value)
+		}
+		w := serve(h, r)
+		if w.Code != tt.status {
+			t.Errorf("%d: expected status %d, got %d: %s", i, tt.status, w.Code, w.Body)
+		}
+		if tt.status == http.StatusOK && w.Body.String() != body {
+			t.Errorf("%d: expected body %q, got %q", i, body, w.Body)
+		}
+	}
+
+	if w := serve(h, httptest.NewRequest("GET", "/", nil)); w.Code != http.StatusOK {
+		t.Errorf("request without body rejected with %d", w.Code)
+	}
+}
+
+func TestHandlerResponse(t *testing.T) {
+	h := Handler(echo, nil)
+	r := httptest.NewRequest("POST", "/", strings.NewReader("BLAKE"))
+	r.Header.Set(WantContentDigest, "blake-256=10, blake-512=1")
+	r.Header.Set(WantReprDigest, "blake-512=1")
+	w := serve(h, r)
+	want := "blake-256=:B2Y+AM+W+8E2z3se4JnJU0a6OSCJPRjMiFHyLuLjaqY=:"
+	if got := w.Header().Get(ContentDigest); got != want {
+		t.Errorf("expected Content-Digest %s, got %s", want, got)
+	}
+	if got := w.Header().Get(ReprDigest); got != (Digests{BLAKE512: BLAKE512.Sum([]byte("BLAKE"))}).String() {
+		t.Errorf("unexpected Repr-Digest %s", got)
+	}
+	if w.Body.String() != "BLAKE" {
+		t.Errorf("unexpected body %q", w.Body)
+	}
+
+	w = serve(h, httptest.NewRequest("GET", "/", nil))
+	if w.Header().Get(ContentDigest) != "" {
+		t.Error("Content-Digest sent without being asked for")
+	}
+	w = serve(Handler(echo, &Options{ResponseAlgorithm: BLAKE512}), httptest.NewRequest("GET", "/", nil))
+	if got := w.Header().Get(ContentDigest); got != (Digests{BLAKE512: BLAKE512.Sum(nil)}).String() {
+		t.Errorf("unexpected Content-Digest %s", got)
+	}
+
+	gz := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write([]byte("not really gzip"))
+	})
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(WantReprDigest, "blake-256=1")
+	if w = serve(Handler(gz, nil), r); w.Header().Get(ReprDigest) != "" {
+		t.Error("Repr-Digest computed over content-coded body")
+	}
+}
+
+func TestHandlerMismatchAfterWrite(t *testing.T) {
+	wrote := make(chan error, 1)
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		io.ReadAll(r.Body)
+		_, err := w.Write([]byte("late"))
+		wrote <- err
+	}), nil)
+	r := httptest.NewRequest("PUT", "/", strings.NewReader("Golang"))
+	r.Header.Set(ContentDigest, Digests{BLAKE256: BLAKE256.Sum(nil)}.String())
+	w := serve(h, r)
+	if w.Code != http.StatusAccepted || w.Body.Len() != 0 {
+		t.Errorf("unexpected response %d %q", w.Code, w.Body)
+	}
+	if err := <-wrote; err != ErrMismatch {
+		t.Errorf("expected ErrMismatch from Write, got %v", err)
+	}
+}
+
+func TestHandlerWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	var got http.ResponseWriter
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = w
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush: %v", err)
+		}
+	}), nil)
+
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if got != rec {
+		t.Error("response without digests is wrapped")
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(WantContentDigest, "blake-256=1")
+	h.ServeHTTP(rec, r)
+	if rw, ok := got.(*responseWriter); !ok || rw.Unwrap() != rec {
+		t.Error("response with digests does not unwrap to the original writer")
+	}
+}
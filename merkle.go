@@ -0,0 +1,265 @@
+package blake
+
+import (
+	"errors"
+)
+
+// Domain separation prefixes for Merkle tree hashing as defined
+// in RFC 6962 and RFC 9162.
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+var (
+	// ErrInvalidProof is returned when a Merkle proof does not
+	// verify against the given root.
+	ErrInvalidProof = errors.New("blake: invalid Merkle proof")
+
+	// ErrTreeSize is returned when a requested leaf index or
+	// tree size is not covered by the tree.
+	ErrTreeSize = errors.New("blake: index or tree size out of range")
+)
+
+// Precomputed states which have already absorbed the domain
+// separation byte. They are copied by value for every hash.
+var (
+	merkleLeafState = prefixState256([]byte{merkleLeafPrefix})
+	merkleNodeState = prefixState256([]byte{merkleNodePrefix})
+)
+
+// prefixState256 returns a BLAKE-256 state which has absorbed p.
+func prefixState256(p []byte) digest256 {
+	var d digest256
+	d.Reset()
+	d.Write(p)
+	return d
+}
+
+// MerkleLeafHash returns the BLAKE-256 hash of a Merkle tree leaf,
+// computed as H(0x00 || data).
+func MerkleLeafHash(data []byte) [Size256]byte {
+	d := merkleLeafState
+	d.Write(data)
+	return d.checkSum()
+}
+
+// MerkleNodeHash returns the BLAKE-256 hash of an interior Merkle
+// tree node, computed as H(0x01 || left || right).
+func MerkleNodeHash(left, right [Size256]byte) [Size256]byte {
+	d := merkleNodeState
+	d.Write(left[:])
+	d.Write(right[:])
+	return d.checkSum()
+}
+
+// MerkleTree is an append-only Merkle tree over BLAKE-256 following
+// RFC 6962. It stores the leaf hashes and computes roots and proofs
+// for the current or any earlier tree size.
+type MerkleTree struct {
+	leaves [][Size256]byte
+}
+
+// NewMerkleTree returns an empty MerkleTree.
+func NewMerkleTree() *MerkleTree {
+	return new(MerkleTree)
+}
+
+// Append hashes data as a leaf, appends it to the tree and returns
+// its index.
+func (t *MerkleTree) Append(data []byte) uint64 {
+	return t.AppendHash(MerkleLeafHash(data))
+}
+
+// AppendHash appends an already computed leaf hash to the tree and
+// returns its index.
+func (t *MerkleTree) AppendHash(leaf [Size256]byte) uint64 {
+	t.leaves = append(t.leaves, leaf)
+	return uint64(len(t.leaves) - 1)
+}
+
+// Size returns the number of leaves in the tree.
+func (t *MerkleTree) Size() uint64 { return uint64(len(t.leaves)) }
+
+// LeafHash returns the hash of the leaf at the given index.
+func (t *MerkleTree) LeafHash(index uint64) ([Size256]byte, error) {
+	if index >= t.Size() {
+		return [Size256]byte{}, ErrTreeSize
+	}
+	return t.leaves[index], nil
+}
+
+// Root returns the root hash of the tree. The root of an empty
+// tree is the BLAKE-256 hash of the empty string.
+func (t *MerkleTree) Root() [Size256]byte {
+	return merkleRoot(t.leaves)
+}
+
+// RootAt returns the root hash of the tree as it was when it
+// contained size leaves.
+func (t *MerkleTree) RootAt(size uint64) ([Size256]byte, error) {
+	if size > t.Size() {
+		return [Size256]byte{}, ErrTreeSize
+	}
+	return merkleRoot(t.leaves[:size]), nil
+}
+
+// InclusionProof returns the audit path for the leaf at index in
+// the tree of the given size, ordered from the leaf to the root.
+func (t *MerkleTree) InclusionProof(index, size uint64) ([][Size256]byte, error) {
+	if size > t.Size() || index >= size {
+		return nil, ErrTreeSize
+	}
+	return merklePath(nil, index, t.leaves[:size]), nil
+}
+
+// ConsistencyProof returns the proof that the tree of size n is an
+// append-only extension of the tree of size m.
+func (t *MerkleTree) ConsistencyProof(m, n uint64) ([][Size256]byte, error) {
+	if n > t.Size() || m > n {
+		return nil, ErrTreeSize
+	}
+	if m == 0 || m == n {
+		return nil, nil
+	}
+	return merkleSubproof(nil, m, t.leaves[:n], true), nil
+}
+
+// merkleSplit returns the largest power of two smaller than n.
+// n must be greater than one.
+func merkleSplit(n uint64) uint64 {
+	k := uint64(1)
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// merkleRoot computes MTH(D[n]) of the given leaf hashes.
+func merkleRoot(leaves [][Size256]byte) [Size256]byte {
+	switch len(leaves) {
+	case 0:
+		return Sum256(nil)
+	case 1:
+		return leaves[0]
+	}
+	k := merkleSplit(uint64(len(leaves)))
+	return MerkleNodeHash(merkleRoot(leaves[:k]), merkleRoot(leaves[k:]))
+}
+
+// merklePath appends PATH(m, D[n]) to proof.
+func merklePath(proof [][Size256]byte, m uint64, leaves [][Size256]byte) [][Size256]byte {
+	n := uint64(len(leaves))
+	if n <= 1 {
+		return proof
+	}
+	k := merkleSplit(n)
+	if m < k {
+		proof = merklePath(proof, m, leaves[:k])
+		return append(proof, merkleRoot(leaves[k:]))
+	}
+	proof = merklePath(proof, m-k, leaves[k:])
+	return append(proof, merkleRoot(leaves[:k]))
+}
+
+// merkleSubproof appends SUBPROOF(m, D[n], b) to proof.
+func merkleSubproof(proof [][Size256]byte, m uint64, leaves [][Size256]byte, b bool) [][Size256]byte {
+	n := uint64(len(leaves))
+	if m == n {
+		if b {
+			return proof
+		}
+		return append(proof, merkleRoot(leaves))
+	}
+	k := merkleSplit(n)
+	if m <= k {
+		proof = merkleSubproof(proof, m, leaves[:k], b)
+		return append(proof, merkleRoot(leaves[k:]))
+	}
+	proof = merkleSubproof(proof, m-k, leaves[k:], false)
+	return append(proof, merkleRoot(leaves[:k]))
+}
+
+// VerifyMerkleInclusion checks that leaf is the hash of the leaf at
+// index in the tree of the given size with the given root, using the
+// algorithm of RFC 9162, section 2.1.3.2.
+func VerifyMerkleInclusion(leaf [Size256]byte, index, size uint64, proof [][Size256]byte, root [Size256]byte) error {
+	if index >= size {
+		return ErrTreeSize
+	}
+	fn, sn := index, size-1
+	r := leaf
+	for _, p := range proof {
+		if sn == 0 {
+			return ErrInvalidProof
+		}
+		if fn&1 == 1 || fn == sn {
+			r = MerkleNodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = MerkleNodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 || r != root {
+		return ErrInvalidProof
+	}
+	return nil
+}
+
+// VerifyMerkleConsistency checks that the tree of size n with root
+// rootN is an append-only extension of the tree of size m with root
+// rootM, using the algorithm of RFC 9162, section 2.1.4.2.
+func VerifyMerkleConsistency(m, n uint64, rootM, rootN [Size256]byte, proof [][Size256]byte) error {
+	switch {
+	case m > n:
+		return ErrTreeSize
+	case m == n:
+		if len(proof) != 0 || rootM != rootN {
+			return ErrInvalidProof
+		}
+		return nil
+	case m == 0:
+		if len(proof) != 0 {
+			return ErrInvalidProof
+		}
+		return nil
+	case len(proof) == 0:
+		return ErrInvalidProof
+	}
+
+	if m&(m-1) == 0 {
+		proof = append([][Size256]byte{rootM}, proof...)
+	}
+	fn, sn := m-1, n-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+	fr, sr := proof[0], proof[0]
+	for _, c := range proof[1:] {
+		if sn == 0 {
+			return ErrInvalidProof
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = MerkleNodeHash(c, fr)
+			sr = MerkleNodeHash(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = MerkleNodeHash(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 || fr != rootM || sr != rootN {
+		return ErrInvalidProof
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package blake
+
+import (
+	"fmt"
+	"testing"
+)
+
+func newTestTree(n int) *MerkleTree {
+	t := NewMerkleTree()
+	for i := 0; i < n; i++ {
+		t.Append([]byte(fmt.Sprintf("leaf %d", i)))
+	}
+	return t
+}
+
+func TestMerkleHashes(t *testing.T) {
+	data := []byte("Golang")
+	if MerkleLeafHash(data) != Sum256(append([]byte{0x00}, data...)) {
+		t.Error("leaf hash differs from H(0x00 || data)")
+	}
+	l, r := Sum256([]byte("l")), Sum256([]byte("r"))
+	in := append([]byte{0x01}, l[:]...)
+	in = append(in, r[:]...)
+	if MerkleNodeHash(l, r) != Sum256(in) {
+		t.Error("node hash differs from H(0x01 || left || right)")
+	}
+	if n := testing.AllocsPerRun(10, func() { MerkleNodeHash(l, r) }); n != 0 {
+		t.Errorf("MerkleNodeHash allocates %v times", n)
+	}
+}
+
+func TestMerkleRoot(t *testing.T) {
+	tree := NewMerkleTree()
+	if tree.Root() != Sum256(nil) {
+		t.Error("empty tree root differs from the hash of the empty string")
+	}
+	tree.Append([]byte("a"))
+	tree.Append([]byte("b"))
+	tree.Append([]byte("c"))
+	a, b, c := MerkleLeafHash([]byte("a")), MerkleLeafHash([]byte("b")), MerkleLeafHash([]byte("c"))
+	if want := MerkleNodeHash(MerkleNodeHash(a, b), c); tree.Root() != want {
+		t.Errorf("expected root %x, got %x", want, tree.Root())
+	}
+	if root, _ := tree.RootAt(1); root != a {
+		t.Errorf("expected root %x for size 1, got %x", a, root)
+	}
+	if _, err := tree.RootAt(4); err != ErrTreeSize {
+		t.Errorf("expected ErrTreeSize, got %v", err)
+	}
+}
+
+func TestMerkleInclusion(t *testing.T) {
+	tree := newTestTree(33)
+	for n := uint64(1); n <= tree.Size(); n++ {
+		root, _ := tree.RootAt(n)
+		for m := uint64(0); m < n; m++ {
+			proof, err := tree.InclusionProof(m, n)
+			if err != nil {
+				t.Fatalf("InclusionProof(%d, %d): %v", m, n, err)
+			}
+			leaf, _ := tree.LeafHash(m)
+			if err := VerifyMerkleInclusion(leaf, m, n, proof, root); err != nil {
+				t.Errorf("VerifyMerkleInclusion(%d, %d): %v", m, n, err)
+			}
+			if len(proof) > 0 {
+				proof[0][0] ^= 1
+				if VerifyMerkleInclusion(leaf, m, n, proof, root) == nil {
+					t.Errorf("tampered proof for (%d, %d) verified", m, n)
+				}
+				proof[0][0] ^= 1
+			}
+			if VerifyMerkleInclusion(leaf, m, n, append(proof, leaf), root) == nil {
+				t.Errorf("extended proof for (%d, %d) verified", m, n)
+			}
+		}
+	}
+	if _, err := tree.InclusionProof(5, 5); err != ErrTreeSize {
+		t.Errorf("expected ErrTreeSize, got %v", err)
+	}
+}
+
+func TestMerkleConsistency(t *testing.T) {
+	tree := newTestTree(33)
+	for n := uint64(1); n <= tree.Size(); n++ {
+		rootN, _ := tree.RootAt(n)
+		for m := uint64(1); m <= n; m++ {
+			rootM, _ := tree.RootAt(m)
+			proof, err := tree.ConsistencyProof(m, n)
+			if err != nil {
+				t.Fatalf("ConsistencyProof(%d, %d): %v", m, n, err)
+			}
+			if err := VerifyMerkleConsistency(m, n, rootM, rootN, proof); err != nil {
+				t.Errorf("VerifyMerkleConsistency(%d, %d): %v", m, n, err)
+			}
+			if m == n {
+				continue
+			}
+			if VerifyMerkleConsistency(m, n, rootN, rootN, proof) == nil {
+				t.Errorf("consistency (%d, %d) verified with wrong old root", m, n)
+			}
+			proof[len(proof)-1][0] ^= 1
+			if VerifyMerkleConsistency(m, n, rootM, rootN, proof) == nil {
+				t.Errorf("tampered proof for (%d, %d) verified", m, n)
+			}
+		}
+	}
+}
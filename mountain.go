@@ -0,0 +1,299 @@
+package blake
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"sync"
+)
+
+// MountainStore persists the nodes of a MountainRange by position.
+// Positions are zero-based and follow the post-order in which nodes
+// are appended.
+type MountainStore interface {
+	// Get returns the hash stored at pos.
+	Get(pos uint64) ([Size256]byte, error)
+
+	// Put stores the hash at pos.
+	Put(pos uint64, hash [Size256]byte) error
+}
+
+// MemoryMountainStore is a MountainStore backed by a slice. It is
+// safe for concurrent use.
+type MemoryMountainStore struct {
+	mu    sync.RWMutex
+	nodes [][Size256]byte
+}
+
+// NewMemoryMountainStore returns an empty MemoryMountainStore.
+func NewMemoryMountainStore() *MemoryMountainStore {
+	return new(MemoryMountainStore)
+}
+
+func (s *MemoryMountainStore) Get(pos uint64) ([Size256]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if pos >= uint64(len(s.nodes)) {
+		return [Size256]byte{}, ErrNodeNotFound
+	}
+	return s.nodes[pos], nil
+}
+
+func (s *MemoryMountainStore) Put(pos uint64, hash [Size256]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for uint64(len(s.nodes)) <= pos {
+		s.nodes = append(s.nodes, [Size256]byte{})
+	}
+	s.nodes[pos] = hash
+	return nil
+}
+
+// MountainLeafPos returns the position of the leaf with the given
+// index.
+func MountainLeafPos(index uint64) uint64 {
+	return 2*index - uint64(bits.OnesCount64(index))
+}
+
+// MountainSize returns the number of nodes in a Merkle Mountain
+// Range with the given number of leaves.
+func MountainSize(leaves uint64) uint64 {
+	return 2*leaves - uint64(bits.OnesCount64(leaves))
+}
+
+// MountainHeight returns the height of the node at pos. Leaves have
+// height zero.
+func MountainHeight(pos uint64) int {
+	pos++
+	for pos&(pos+1) != 0 {
+		pos -= 1<<uint(bits.Len64(pos)-1) - 1
+	}
+	return bits.Len64(pos) - 1
+}
+
+// MountainPeaks returns the positions of the peaks of a Merkle
+// Mountain Range with the given number of leaves, from left to right.
+func MountainPeaks(leaves uint64) []uint64 {
+	var peaks []uint64
+	pos := uint64(0)
+	for h := bits.Len64(leaves) - 1; h >= 0; h-- {
+		if leaves&(1<<uint(h)) != 0 {
+			pos += 1<<uint(h+1) - 1
+			peaks = append(peaks, pos-1)
+		}
+	}
+	return peaks
+}
+
+// MountainRange is an append-only Merkle Mountain Range over
+// BLAKE-256. Leaves are hashed with MerkleLeafHash and parents with
+// MerkleNodeHash. The root bags the peaks from right to left and
+// commits to the number of leaves.
+type MountainRange struct {
+	store  MountainStore
+	leaves uint64
+}
+
+// NewMountainRange returns an empty MountainRange backed by store.
+// If store is nil, a MemoryMountainStore is used.
+func NewMountainRange(store MountainStore) *MountainRange {
+	return LoadMountainRange(store, 0)
+}
+
+// LoadMountainRange returns a MountainRange with the given number of
+// leaves whose nodes are held in store.
+func LoadMountainRange(store MountainStore, leaves uint64) *MountainRange {
+	if store == nil {
+		store = NewMemoryMountainStore()
+	}
+	return &MountainRange{store: store, leaves: leaves}
+}
+
+// Leaves returns the number of leaves.
+func (m *MountainRange) Leaves() uint64 { return m.leaves }
+
+// Append hashes data as a leaf, appends it and returns its index.
+func (m *MountainRange) Append(data []byte) (uint64, error) {
+	return m.AppendHash(MerkleLeafHash(data))
+}
+
+// AppendHash appends an already computed leaf hash and returns its
+// index.
+func (m *MountainRange) AppendHash(leaf [Size256]byte) (uint64, error) {
+	pos := MountainSize(m.leaves)
+	if err := m.store.Put(pos, leaf); err != nil {
+		return 0, err
+	}
+	h := leaf
+	for height := 0; MountainHeight(pos+1) > height; height++ {
+		left, err := m.store.Get(pos + 1 - 2<<uint(height))
+		if err != nil {
+			return 0, err
+		}
+		pos++
+		h = MerkleNodeHash(left, h)
+		if err := m.store.Put(pos, h); err != nil {
+			return 0, err
+		}
+	}
+	m.leaves++
+	return m.leaves - 1, nil
+}
+
+// Root returns the root hash of the range.
+func (m *MountainRange) Root() ([Size256]byte, error) {
+	return m.RootAt(m.leaves)
+}
+
+// RootAt returns the root hash of the range as it was when it held
+// the given number of leaves. The root of an empty range is the
+// BLAKE-256 hash of the empty string.
+func (m *MountainRange) RootAt(leaves uint64) ([Size256]byte, error) {
+	if leaves > m.leaves {
+		return [Size256]byte{}, ErrTreeSize
+	}
+	peaks, err := m.peakHashes(MountainPeaks(leaves))
+	if err != nil {
+		return [Size256]byte{}, err
+	}
+	return bagPeaks(leaves, peaks), nil
+}
+
+func (m *MountainRange) peakHashes(pos []uint64) ([][Size256]byte, error) {
+	peaks := make([][Size256]byte, len(pos))
+	for i, p := range pos {
+		h, err := m.store.Get(p)
+		if err != nil {
+			return nil, err
+		}
+		peaks[i] = h
+	}
+	return peaks, nil
+}
+
+// mountainRootState has absorbed the domain separation byte of
+// bagged Merkle Mountain Range roots.
+var mountainRootState = prefixState256([]byte{0x02})
+
+// bagPeaks folds the peak hashes from right to left and commits to
+// the number of leaves as H(0x02 || uint64(leaves) || bag).
+func bagPeaks(leaves uint64, peaks [][Size256]byte) [Size256]byte {
+	if len(peaks) == 0 {
+		return Sum256(nil)
+	}
+	r := peaks[len(peaks)-1]
+	for i := len(peaks) - 2; i >= 0; i-- {
+		r = MerkleNodeHash(peaks[i], r)
+	}
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], leaves)
+	d := mountainRootState
+	d.Write(n[:])
+	d.Write(r[:])
+	return d.checkSum()
+}
+
+// mountainStep returns the sibling and parent of the node at pos with
+// the given height.
+func mountainStep(pos uint64, height int) (sibling, parent uint64, right bool) {
+	if MountainHeight(pos+1) > height {
+		return pos + 1 - 2<<uint(height), pos + 1, true
+	}
+	sibling = pos + 2<<uint(height) - 1
+	return sibling, sibling + 1, false
+}
+
+// MountainProof proves the inclusion of a leaf in a MountainRange.
+type MountainProof struct {
+	// Path holds the siblings from the leaf up to its peak.
+	Path [][Size256]byte
+
+	// Peaks holds the hashes of the other peaks, from left to right.
+	Peaks [][Size256]byte
+}
+
+// Prove returns a proof that the leaf with the given index is
+// included in the range as it was when it held the given number of
+// leaves.
+func (m *MountainRange) Prove(index, leaves uint64) (*MountainProof, error) {
+	if index >= leaves || leaves > m.leaves {
+		return nil, ErrTreeSize
+	}
+	peaks := MountainPeaks(leaves)
+	pos := MountainLeafPos(index)
+	p := new(MountainProof)
+	for height := 0; ; height++ {
+		if containsUint64(peaks, pos) {
+			break
+		}
+		sibling, parent, _ := mountainStep(pos, height)
+		h, err := m.store.Get(sibling)
+		if err != nil {
+			return nil, err
+		}
+		p.Path = append(p.Path, h)
+		pos = parent
+	}
+	for _, peak := range peaks {
+		if peak == pos {
+			continue
+		}
+		h, err := m.store.Get(peak)
+		if err != nil {
+			return nil, err
+		}
+		p.Peaks = append(p.Peaks, h)
+	}
+	return p, nil
+}
+
+func containsUint64(s []uint64, v uint64) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyMountainProof checks that leaf is the hash of the leaf with
+// the given index in the range of the given number of leaves whose
+// root is root.
+func VerifyMountainProof(leaf [Size256]byte, index, leaves uint64, proof *MountainProof, root [Size256]byte) error {
+	if index >= leaves {
+		return ErrTreeSize
+	}
+	peaks := MountainPeaks(leaves)
+	if len(proof.Peaks) != len(peaks)-1 {
+		return ErrInvalidProof
+	}
+	pos := MountainLeafPos(index)
+	h := leaf
+	for height, sib := range proof.Path {
+		if containsUint64(peaks, pos) {
+			return ErrInvalidProof
+		}
+		_, parent, right := mountainStep(pos, height)
+		if right {
+			h = MerkleNodeHash(sib, h)
+		} else {
+			h = MerkleNodeHash(h, sib)
+		}
+		pos = parent
+	}
+	bag := make([][Size256]byte, 0, len(peaks))
+	others := proof.Peaks
+	for _, peak := range peaks {
+		if peak == pos {
+			bag = append(bag, h)
+			continue
+		}
+		if len(others) == 0 {
+			return ErrInvalidProof
+		}
+		bag, others = append(bag, others[0]), others[1:]
+	}
+	if len(bag) != len(peaks) || bagPeaks(leaves, bag) != root {
+		return ErrInvalidProof
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package blake
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMountainPositions(t *testing.T) {
+	heights := []int{0, 0, 1, 0, 0, 1, 2, 0, 0, 1, 0, 0, 1, 2, 3, 0}
+	for pos, h := range heights {
+		if got := MountainHeight(uint64(pos)); got != h {
+			t.Errorf("MountainHeight(%d) = %d, want %d", pos, got, h)
+		}
+	}
+	leafPos := []uint64{0, 1, 3, 4, 7, 8, 10, 11, 15}
+	for i, pos := range leafPos {
+		if got := MountainLeafPos(uint64(i)); got != pos {
+			t.Errorf("MountainLeafPos(%d) = %d, want %d", i, got, pos)
+		}
+	}
+	if got := MountainSize(11); got != 19 {
+		t.Errorf("MountainSize(11) = %d, want 19", got)
+	}
+	if got := fmt.Sprint(MountainPeaks(11)); got != "[14 17 18]" {
+		t.Errorf("MountainPeaks(11) = %s, want [14 17 18]", got)
+	}
+}
+
+func TestMountainRoot(t *testing.T) {
+	m := NewMountainRange(nil)
+	if root, _ := m.Root(); root != Sum256(nil) {
+		t.Error("empty range root differs from the hash of the empty string")
+	}
+	var leaves [][Size256]byte
+	for i := 0; i < 7; i++ {
+		data := []byte(fmt.Sprintf("leaf %d", i))
+		leaves = append(leaves, MerkleLeafHash(data))
+		if idx, err := m.Append(data); err != nil || idx != uint64(i) {
+			t.Fatalf("Append = %d, %v", idx, err)
+		}
+	}
+	n := MerkleNodeHash
+	p0 := n(n(leaves[0], leaves[1]), n(leaves[2], leaves[3]))
+	p1 := n(leaves[4], leaves[5])
+	bag := n(p0, n(p1, leaves[6]))
+	want := Sum256(append([]byte{0x02, 0, 0, 0, 0, 0, 0, 0, 7}, bag[:]...))
+	if root, _ := m.Root(); root != want {
+		t.Errorf("expected root %x, got %x", want, root)
+	}
+	want = Sum256(append([]byte{0x02, 0, 0, 0, 0, 0, 0, 0, 4}, p0[:]...))
+	if root, _ := m.RootAt(4); root != want {
+		t.Errorf("expected root %x for 4 leaves, got %x", want, root)
+	}
+}
+
+func TestMountainProof(t *testing.T) {
+	store := NewMemoryMountainStore()
+	m := NewMountainRange(store)
+	for i := 0; i < 40; i++ {
+		m.Append([]byte(fmt.Sprintf("leaf %d", i)))
+	}
+	m = LoadMountainRange(store, m.Leaves())
+	for leaves := uint64(1); leaves <= m.Leaves(); leaves++ {
+		root, err := m.RootAt(leaves)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := uint64(0); i < leaves; i++ {
+			leaf := MerkleLeafHash([]byte(fmt.Sprintf("leaf %d", i)))
+			p, err := m.Prove(i, leaves)
+			if err != nil {
+				t.Fatalf("Prove(%d, %d): %v", i, leaves, err)
+			}
+			if err := VerifyMountainProof(leaf, i, leaves, p, root); err != nil {
+				t.Errorf("VerifyMountainProof(%d, %d): %v", i, leaves, err)
+			}
+			if VerifyMountainProof(leaf, i, leaves+1, p, root) == nil {
+				t.Errorf("proof (%d, %d) verified for a different size", i, leaves)
+			}
+			if len(p.Path) > 0 {
+				p.Path = p.Path[:len(p.Path)-1]
+				if VerifyMountainProof(leaf, i, leaves, p, root) == nil {
+					t.Errorf("truncated proof (%d, %d) verified", i, leaves)
+				}
+			}
+		}
+	}
+	if _, err := m.Prove(40, 40); err != ErrTreeSize {
+		t.Errorf("expected ErrTreeSize, got %v", err)
+	}
+}
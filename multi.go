@@ -0,0 +1,167 @@
+package blake
+
+import (
+	"context"
+	"io"
+)
+
+// Digests holds the checksums computed by a MultiHasher. Checksums
+// which were not requested are all zero.
+type Digests struct {
+	Sum224 [Size224]byte
+	Sum256 [Size256]byte
+	Sum384 [Size384]byte
+	Sum512 [Size512]byte
+}
+
+// MultiHasher computes several BLAKE checksums of a single stream.
+// Input is buffered once in BLAKE-384/512 sized blocks, which are also
+// whole BLAKE-224/256 blocks, and every full block is handed directly
+// to the compression function of each requested variant.
+type MultiHasher struct {
+	d224, d256 *digest256
+	d384, d512 *digest512
+	x          [BlockSize512]byte
+	nx         int
+}
+
+// NewMultiHasher returns a MultiHasher computing the checksums of the
+// given sizes, which must be Size224, Size256, Size384 or Size512. With
+// no sizes, all four checksums are computed.
+func NewMultiHasher(sizes ...int) *MultiHasher {
+	if len(sizes) == 0 {
+		sizes = []int{Size224, Size256, Size384, Size512}
+	}
+	m := new(MultiHasher)
+	for _, size := range sizes {
+		switch size {
+		case Size224:
+			m.d224 = &digest256{is224: true}
+		case Size256:
+			m.d256 = new(digest256)
+		case Size384:
+			m.d384 = &digest512{is384: true}
+		case Size512:
+			m.d512 = new(digest512)
+		default:
+			panic("blake: unsupported checksum size")
+		}
+	}
+	m.Reset()
+	return m
+}
+
+// Reset resets the MultiHasher to its initial state.
+func (m *MultiHasher) Reset() {
+	for _, d := range []*digest256{m.d224, m.d256} {
+		if d != nil {
+			d.Reset()
+		}
+	}
+	for _, d := range []*digest512{m.d384, m.d512} {
+		if d != nil {
+			d.Reset()
+		}
+	}
+	m.nx = 0
+}
+
+// blocks feeds p, a multiple of BlockSize512 bytes long, to every
+// requested variant.
+func (m *MultiHasher) blocks(p []byte) {
+	if m.d224 != nil {
+		block256(m.d224, p)
+	}
+	if m.d256 != nil {
+		block256(m.d256, p)
+	}
+	if m.d384 != nil {
+		block512(m.d384, p)
+	}
+	if m.d512 != nil {
+		block512(m.d512, p)
+	}
+}
+
+func (m *MultiHasher) Write(p []byte) (nn int, err error) {
+	nn = len(p)
+	if m.nx > 0 {
+		n := copy(m.x[m.nx:], p)
+		m.nx += n
+		if m.nx == BlockSize512 {
+			m.blocks(m.x[:])
+			m.nx = 0
+		}
+		p = p[n:]
+	}
+	if len(p) >= BlockSize512 {
+		n := len(p) &^ (BlockSize512 - 1)
+		m.blocks(p[:n])
+		p = p[n:]
+	}
+	if len(p) > 0 {
+		m.nx = copy(m.x[:], p)
+	}
+	return
+}
+
+// ReadFrom implements io.ReaderFrom.
+func (m *MultiHasher) ReadFrom(r io.Reader) (int64, error) {
+	return readFrom(context.Background(), m, r)
+}
+
+// Sum appends the checksum of the given size to b. It returns b
+// unchanged if that checksum was not requested.
+func (m *MultiHasher) Sum(b []byte, size int) []byte {
+	var d256 *digest256
+	var d512 *digest512
+	switch size {
+	case Size224:
+		d256 = m.d224
+	case Size256:
+		d256 = m.d256
+	case Size384:
+		d512 = m.d384
+	case Size512:
+		d512 = m.d512
+	}
+	if d256 != nil {
+		d := *d256
+		d.Write(m.x[:m.nx])
+		return d.Sum(b)
+	}
+	if d512 != nil {
+		d := *d512
+		d.Write(m.x[:m.nx])
+		return d.Sum(b)
+	}
+	return b
+}
+
+// Digests returns the requested checksums of the data written so far.
+// It does not change the state of the MultiHasher.
+func (m *MultiHasher) Digests() (ds Digests) {
+	if m.d224 != nil {
+		d := *m.d224
+		d.Write(m.x[:m.nx])
+		sum := d.checkSum()
+		copy(ds.Sum224[:], sum[:Size224])
+	}
+	if m.d256 != nil {
+		d := *m.d256
+		d.Write(m.x[:m.nx])
+		ds.Sum256 = d.checkSum()
+	}
+	if m.d384 != nil {
+		d := *m.d384
+		d.Write(m.x[:m.nx])
+		sum := d.checkSum()
+		copy(ds.Sum384[:], sum[:Size384])
+	}
+	if m.d512 != nil {
+		d := *m.d512
+		d.Write(m.x[:m.nx])
+		ds.Sum512 = d.checkSum()
+	}
+	return
+}
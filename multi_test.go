@@ -0,0 +1,56 @@
+package blake
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMultiHasher(t *testing.T) {
+	for _, n := range []int{0, 1, 55, 63, 64, 111, 127, 128, 129, 1000} {
+		data := bytes.Repeat([]byte{'b'}, n)
+		m := NewMultiHasher()
+		// Write in uneven pieces to exercise the buffering.
+		for p := data; len(p) > 0; {
+			k := 1 + len(p)%37
+			if k > len(p) {
+				k = len(p)
+			}
+			m.Write(p[:k])
+			p = p[k:]
+		}
+		ds := m.Digests()
+		if ds.Sum224 != Sum224(data) || ds.Sum256 != Sum256(data) || ds.Sum384 != Sum384(data) || ds.Sum512 != Sum512(data) {
+			t.Errorf("%d bytes: digests differ from the one-shot functions", n)
+		}
+		want := Sum384(data)
+		if !bytes.Equal(m.Sum(nil, Size384), want[:]) {
+			t.Errorf("%d bytes: Sum(Size384) differs from Sum384", n)
+		}
+	}
+}
+
+func TestMultiHasherSubset(t *testing.T) {
+	m := NewMultiHasher(Size256, Size512)
+	m.ReadFrom(strings.NewReader("Golang"))
+	ds := m.Digests()
+	if ds.Sum256 != Sum256([]byte("Golang")) || ds.Sum512 != Sum512([]byte("Golang")) {
+		t.Error("requested digests are wrong")
+	}
+	if ds.Sum224 != [Size224]byte{} || m.Sum(nil, Size224) != nil {
+		t.Error("unrequested BLAKE-224 digest was computed")
+	}
+
+	m.Reset()
+	m.Write([]byte("BLAKE"))
+	if m.Digests().Sum256 != Sum256([]byte("BLAKE")) {
+		t.Error("Reset did not clear the state")
+	}
+
+	defer func() {
+		if err := recover(); err == nil {
+			t.Error("expected panic for unsupported size")
+		}
+	}()
+	NewMultiHasher(20)
+}
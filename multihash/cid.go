@@ -0,0 +1,160 @@
+package multihash
+
+import (
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// Content codecs of a CID.
+const (
+	Raw     uint64 = 0x55
+	DagPB   uint64 = 0x70
+	DagCBOR uint64 = 0x71
+)
+
+// Base is a multibase prefix used to format a CID as a string.
+type Base byte
+
+// Supported multibase encodings.
+const (
+	Base32    Base = 'b' // RFC 4648 lowercase base32 without padding
+	Base58BTC Base = 'z' // Bitcoin base58
+)
+
+var (
+	// ErrCID is returned for malformed content identifiers.
+	ErrCID = errors.New("multihash: invalid CID")
+
+	// ErrBase is returned by CID.Encode for unsupported multibases.
+	ErrBase = errors.New("multihash: unsupported multibase")
+)
+
+var base32Lower = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// CID is a version 1 content identifier:
+//
+//	varint(1) || varint(codec) || multihash
+type CID struct {
+	Codec uint64
+	Hash  Multihash
+}
+
+// SumCID hashes data with BLAKE-256 and returns its CID with the Raw
+// codec.
+func SumCID(data []byte) CID {
+	m, _ := Sum(data, BLAKE256, -1)
+	return CID{Raw, m}
+}
+
+// Bytes returns the binary form of c.
+func (c CID) Bytes() []byte {
+	b := make([]byte, 0, 1+maxVarintLen+len(c.Hash))
+	b = binary.AppendUvarint(b, 1)
+	b = binary.AppendUvarint(b, c.Codec)
+	return append(b, c.Hash...)
+}
+
+// String returns c in base32, the default for CIDv1.
+func (c CID) String() string {
+	s, _ := c.Encode(Base32)
+	return s
+}
+
+// Encode returns c as a multibase string in the given base, or ErrBase
+// if the base is not supported.
+func (c CID) Encode(base Base) (string, error) {
+	switch base {
+	case Base32:
+		return string(Base32) + base32Lower.EncodeToString(c.Bytes()), nil
+	case Base58BTC:
+		return string(Base58BTC) + encodeBase58(c.Bytes()), nil
+	}
+	return "", ErrBase
+}
+
+// ParseCID parses a CIDv1 string in base32 or base58btc.
+func ParseCID(s string) (CID, error) {
+	if s == "" {
+		return CID{}, ErrCID
+	}
+	var b []byte
+	var err error
+	switch Base(s[0]) {
+	case Base32:
+		b, err = base32Lower.DecodeString(s[1:])
+	case 'B':
+		b, err = base32Lower.DecodeString(strings.ToLower(s[1:]))
+	case Base58BTC:
+		b, err = decodeBase58(s[1:])
+	default:
+		return CID{}, ErrCID
+	}
+	if err != nil {
+		return CID{}, ErrCID
+	}
+	return CIDFromBytes(b)
+}
+
+// CIDFromBytes parses the binary form of a CIDv1.
+func CIDFromBytes(b []byte) (CID, error) {
+	v, n, err := readUvarint(b)
+	if err != nil || v != 1 {
+		return CID{}, ErrCID
+	}
+	codec, m, err := readUvarint(b[n:])
+	if err != nil {
+		return CID{}, ErrCID
+	}
+	n += m
+	if _, err := Decode(b[n:]); err != nil {
+		return CID{}, err
+	}
+	return CID{codec, Multihash(append([]byte(nil), b[n:]...))}, nil
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var bigRadix = big.NewInt(58)
+
+// encodeBase58 encodes b with the Bitcoin alphabet, mapping every
+// leading zero byte to '1'.
+func encodeBase58(b []byte) string {
+	zeros := 0
+	for zeros < len(b) && b[zeros] == 0 {
+		zeros++
+	}
+	x := new(big.Int).SetBytes(b)
+	mod := new(big.Int)
+	var out []byte
+	for x.Sign() > 0 {
+		x.DivMod(x, bigRadix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for i := 0; i < zeros; i++ {
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+func decodeBase58(s string) ([]byte, error) {
+	x := new(big.Int)
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	for i := 0; i < len(s); i++ {
+		d := strings.IndexByte(base58Alphabet, s[i])
+		if d < 0 {
+			return nil, ErrCID
+		}
+		x.Mul(x, bigRadix)
+		x.Add(x, big.NewInt(int64(d)))
+	}
+	return append(make([]byte, zeros), x.Bytes()...), nil
+}
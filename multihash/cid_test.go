@@ -0,0 +1,66 @@
+package multihash
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBase58(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{"", ""},
+		{"hello world", "StV1DL6CwTryKyV"},
+		{"\x00\x00\x01", "112"},
+		{"\x00\xff", "15Q"},
+	}
+	for i, tt := range tests {
+		if got := encodeBase58([]byte(tt.in)); got != tt.out {
+			t.Errorf("%d: expected %q, got %q", i, tt.out, got)
+		}
+		if got, err := decodeBase58(tt.out); err != nil || string(got) != tt.in {
+			t.Errorf("%d: decode = %q, %v", i, got, err)
+		}
+	}
+	if _, err := decodeBase58("0OIl"); err == nil {
+		t.Error("expected error for characters outside the alphabet")
+	}
+}
+
+func TestCID(t *testing.T) {
+	c := SumCID([]byte("BLAKE"))
+	s := c.String()
+	if !strings.HasPrefix(s, "bafk") {
+		t.Errorf("raw CIDv1 in base32 should start with bafk, got %s", s)
+	}
+	z, err := c.Encode(Base58BTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range []string{s, "B" + strings.ToUpper(s[1:]), z} {
+		p, err := ParseCID(in)
+		if err != nil {
+			t.Fatalf("ParseCID(%s): %v", in, err)
+		}
+		if p.Codec != Raw || !bytes.Equal(p.Hash, c.Hash) {
+			t.Errorf("ParseCID(%s) = %+v, want %+v", in, p, c)
+		}
+	}
+	if !strings.HasPrefix(z, "z") {
+		t.Error("base58btc CID has no z prefix")
+	}
+	if _, err := c.Encode('f'); err != ErrBase {
+		t.Errorf("expected ErrBase for base16, got %v", err)
+	}
+
+	for _, bad := range []string{"", "x" + s[1:], s[:len(s)-2], "bafk!"} {
+		if _, err := ParseCID(bad); err == nil {
+			t.Errorf("ParseCID(%q): expected error", bad)
+		}
+	}
+	v0 := append([]byte{0, 0x55}, c.Hash...)
+	if _, err := CIDFromBytes(v0); err != ErrCID {
+		t.Errorf("expected ErrCID for version 0, got %v", err)
+	}
+}
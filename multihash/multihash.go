@@ -0,0 +1,181 @@
+// Package multihash encodes BLAKE digests as self-describing multihashes
+// and CIDv1 content identifiers.
+//
+// A multihash is
+//
+//	varint(code) || varint(length) || digest
+//
+// where the varints are unsigned LEB128 in their shortest form. The
+// multicodec table has no entries for the original BLAKE hash functions,
+// so the codes in this package are taken from the private use range
+// 0x300000-0x3fffff and are only meaningful between parties using this
+// package.
+package multihash
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+
+	"github.com/ouzklcn/blake"
+)
+
+// Code identifies a hash function in a multihash.
+type Code uint64
+
+// Codes of the BLAKE variants.
+const (
+	BLAKE224 Code = 0x300000 + 224
+	BLAKE256 Code = 0x300000 + 256
+	BLAKE384 Code = 0x300000 + 384
+	BLAKE512 Code = 0x300000 + 512
+)
+
+type hashFunc struct {
+	name string
+	size int
+	new  func() hash.Hash
+}
+
+var table = map[Code]hashFunc{
+	BLAKE224: {"blake-224", blake.Size224, blake.New224},
+	BLAKE256: {"blake-256", blake.Size256, blake.New256},
+	BLAKE384: {"blake-384", blake.Size384, blake.New384},
+	BLAKE512: {"blake-512", blake.Size512, blake.New512},
+}
+
+// maxVarintLen is the longest varint allowed by the multiformats
+// specification.
+const maxVarintLen = 9
+
+var (
+	// ErrUnknownCode is returned for codes not in the package's table.
+	ErrUnknownCode = errors.New("multihash: unknown hash code")
+	// ErrLength is returned when a digest length is out of range or
+	// does not match the encoded data.
+	ErrLength = errors.New("multihash: invalid digest length")
+	// ErrVarint is returned for malformed varints.
+	ErrVarint = errors.New("multihash: invalid varint")
+)
+
+// Name returns the name of the hash function with the given code, or
+// the empty string if it is unknown.
+func (c Code) Name() string {
+	return table[c].name
+}
+
+func (c Code) String() string {
+	if f, ok := table[c]; ok {
+		return f.name
+	}
+	return fmt.Sprintf("Code(%#x)", uint64(c))
+}
+
+// Size returns the full digest size of the hash function with the given
+// code, or 0 if it is unknown.
+func (c Code) Size() int {
+	return table[c].size
+}
+
+// Multihash is an encoded multihash.
+type Multihash []byte
+
+// Decoded is the decoded form of a multihash.
+type Decoded struct {
+	Code   Code
+	Digest []byte
+}
+
+// Encode returns the multihash of a digest computed with the hash
+// function of the given code. The digest may be a prefix of the full
+// digest, but not empty or longer than it.
+func Encode(digest []byte, code Code) (Multihash, error) {
+	f, ok := table[code]
+	if !ok {
+		return nil, ErrUnknownCode
+	}
+	if len(digest) == 0 || len(digest) > f.size {
+		return nil, ErrLength
+	}
+	m := make([]byte, 0, 2*maxVarintLen+len(digest))
+	m = binary.AppendUvarint(m, uint64(code))
+	m = binary.AppendUvarint(m, uint64(len(digest)))
+	return append(m, digest...), nil
+}
+
+// Sum hashes data with the hash function of the given code and returns
+// the multihash of the first length bytes of the digest. A length of -1
+// keeps the full digest.
+func Sum(data []byte, code Code, length int) (Multihash, error) {
+	f, ok := table[code]
+	if !ok {
+		return nil, ErrUnknownCode
+	}
+	if length == -1 {
+		length = f.size
+	}
+	if length <= 0 || length > f.size {
+		return nil, ErrLength
+	}
+	h := f.new()
+	h.Write(data)
+	return Encode(h.Sum(nil)[:length], code)
+}
+
+// Decode parses a multihash. The returned digest aliases m.
+func Decode(m []byte) (Decoded, error) {
+	d, n, err := readMultihash(m)
+	if err != nil {
+		return Decoded{}, err
+	}
+	if n != len(m) {
+		return Decoded{}, ErrLength
+	}
+	return d, nil
+}
+
+// readMultihash parses a multihash at the start of b and returns the
+// number of bytes it occupies.
+func readMultihash(b []byte) (Decoded, int, error) {
+	code, n, err := readUvarint(b)
+	if err != nil {
+		return Decoded{}, 0, err
+	}
+	f, ok := table[Code(code)]
+	if !ok {
+		return Decoded{}, 0, ErrUnknownCode
+	}
+	length, m, err := readUvarint(b[n:])
+	if err != nil {
+		return Decoded{}, 0, err
+	}
+	n += m
+	if length == 0 || length > uint64(f.size) || length > uint64(len(b)-n) {
+		return Decoded{}, 0, ErrLength
+	}
+	end := n + int(length)
+	return Decoded{Code(code), b[n:end]}, end, nil
+}
+
+// readUvarint reads a varint in its shortest form.
+func readUvarint(b []byte) (uint64, int, error) {
+	v, n := binary.Uvarint(b)
+	if n <= 0 || n > maxVarintLen || (n > 1 && b[n-1] == 0) {
+		return 0, 0, ErrVarint
+	}
+	return v, n, nil
+}
+
+// Verify reports whether m is a valid multihash matching data.
+func (m Multihash) Verify(data []byte) bool {
+	d, err := Decode(m)
+	if err != nil {
+		return false
+	}
+	want, err := Sum(data, d.Code, len(d.Digest))
+	if err != nil {
+		return false
+	}
+	return string(want) == string(m)
+}
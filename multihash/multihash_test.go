@@ -0,0 +1,78 @@
+package multihash
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ouzklcn/blake"
+)
+
+func TestEncode(t *testing.T) {
+	sum := blake.Sum256([]byte("BLAKE"))
+	m, err := Sum([]byte("BLAKE"), BLAKE256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "8082c00120" + hex.EncodeToString(sum[:])
+	if got := hex.EncodeToString(m); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	d, err := Decode(m)
+	if err != nil || d.Code != BLAKE256 || !bytes.Equal(d.Digest, sum[:]) {
+		t.Errorf("Decode = %v, %x, %v", d.Code, d.Digest, err)
+	}
+	if !m.Verify([]byte("BLAKE")) || m.Verify([]byte("blake")) {
+		t.Error("Verify does not match the hashed data")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	for _, code := range []Code{BLAKE224, BLAKE256, BLAKE384, BLAKE512} {
+		full, err := Sum([]byte("Golang"), code, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := Sum([]byte("Golang"), code, 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := Decode(m)
+		if err != nil || len(d.Digest) != 20 {
+			t.Fatalf("%v: Decode = %x, %v", code, d.Digest, err)
+		}
+		fd, _ := Decode(full)
+		if len(fd.Digest) != code.Size() || !bytes.HasPrefix(fd.Digest, d.Digest) {
+			t.Errorf("%v: truncated digest is not a prefix of the full digest", code)
+		}
+		if !m.Verify([]byte("Golang")) {
+			t.Errorf("%v: truncated multihash does not verify", code)
+		}
+		if _, err := Sum(nil, code, code.Size()+1); err != ErrLength {
+			t.Errorf("%v: expected ErrLength for overlong digest, got %v", code, err)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	m, _ := Sum([]byte("BLAKE"), BLAKE224, -1)
+	tests := []struct {
+		in  []byte
+		err error
+	}{
+		{nil, ErrVarint},
+		{m[:len(m)-1], ErrLength},
+		{append(m[:len(m):len(m)], 0), ErrLength},
+		{[]byte{0x12, 0x20}, ErrUnknownCode},
+		{append([]byte{0x80, 0x82, 0xc0, 0x81, 0x00}, m[4:]...), ErrVarint},
+		{[]byte{0x80, 0x82, 0xc0, 0x01, 0x00}, ErrLength},
+	}
+	for i, tt := range tests {
+		if _, err := Decode(tt.in); err != tt.err {
+			t.Errorf("%d: expected %v, got %v", i, tt.err, err)
+		}
+	}
+	if _, err := Encode(make([]byte, 32), Code(0x12)); err != ErrUnknownCode {
+		t.Errorf("expected ErrUnknownCode, got %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+// Package objecthash computes canonical BLAKE-256 digests of Go values
+// by walking them with reflection, in the spirit of ObjectHash.
+//
+// Every value is hashed on its own with a one-byte type tag, and
+// composite values hash the digests of their parts:
+//
+//	nil                    H('n')
+//	bool                   H('b' || "0" or "1")
+//	integers               H('i' || decimal representation)
+//	floats                 H('f' || shortest 'g' representation)
+//	string                 H('u' || UTF-8 bytes)
+//	[]byte, [N]byte        H('r' || bytes)
+//	encoding.TextMarshaler H('t' || text)
+//	slices and arrays      H('l' || H(e0) || H(e1) || ...)
+//	maps and structs       H('d' || sorted H(k) || H(v) pairs)
+//
+// where H is BLAKE-256. Signed and unsigned integers of every width
+// hash alike, and pointers and interfaces hash as the value they refer
+// to. Struct fields are hashed as a map from field name to value, so
+// reordering fields does not change the digest. Unexported fields are
+// ignored, and the field tag
+//
+//	objecthash:"name,omitempty"
+//
+// renames a field, drops it when it holds its zero value, or with
+// "-" skips it entirely. Types implementing Hasher provide their own
+// digest.
+package objecthash
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ouzklcn/blake"
+)
+
+// Digest is the BLAKE-256 digest of a value.
+type Digest = [blake.Size256]byte
+
+// Hasher is implemented by types which compute their own digest.
+type Hasher interface {
+	ObjectHash() (Digest, error)
+}
+
+// MaxDepth limits the nesting of values, which also stops cycles.
+const MaxDepth = 256
+
+var errDepth = errors.New("objecthash: value nested too deeply or cyclic")
+
+var (
+	hasherType        = reflect.TypeOf((*Hasher)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	byteType          = reflect.TypeOf(byte(0))
+)
+
+func sum(tag byte, p []byte) Digest {
+	h := blake.New256()
+	h.Write([]byte{tag})
+	h.Write(p)
+	var d Digest
+	h.Sum(d[:0])
+	return d
+}
+
+// Sum returns the canonical digest of v.
+func Sum(v interface{}) (Digest, error) {
+	return hashValue(reflect.ValueOf(v), 0)
+}
+
+func hashValue(v reflect.Value, depth int) (Digest, error) {
+	if depth > MaxDepth {
+		return Digest{}, errDepth
+	}
+	if !v.IsValid() {
+		return sum('n', nil), nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return sum('n', nil), nil
+		}
+	}
+	if v.Type().Implements(hasherType) {
+		return v.Interface().(Hasher).ObjectHash()
+	}
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return Digest{}, err
+		}
+		return sum('t', text), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return hashValue(v.Elem(), depth+1)
+	case reflect.Bool:
+		if v.Bool() {
+			return sum('b', []byte("1")), nil
+		}
+		return sum('b', []byte("0")), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return sum('i', strconv.AppendInt(nil, v.Int(), 10)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return sum('i', strconv.AppendUint(nil, v.Uint(), 10)), nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if f == 0 {
+			f = 0 // normalize negative zero
+		}
+		if math.IsNaN(f) {
+			return Digest{}, errors.New("objecthash: cannot hash NaN")
+		}
+		return sum('f', strconv.AppendFloat(nil, f, 'g', -1, 64)), nil
+	case reflect.String:
+		return sum('u', []byte(v.String())), nil
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			if v.Type().Elem() == byteType {
+				reflect.Copy(reflect.ValueOf(b), v)
+			} else {
+				// reflect.Copy rejects named byte types.
+				for i := range b {
+					b[i] = byte(v.Index(i).Uint())
+				}
+			}
+			return sum('r', b), nil
+		}
+		buf := make([]byte, 0, v.Len()*blake.Size256)
+		for i := 0; i < v.Len(); i++ {
+			d, err := hashValue(v.Index(i), depth+1)
+			if err != nil {
+				return Digest{}, err
+			}
+			buf = append(buf, d[:]...)
+		}
+		return sum('l', buf), nil
+	case reflect.Map:
+		pairs := make([][]byte, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			p, err := hashPair(iter.Key(), iter.Value(), depth)
+			if err != nil {
+				return Digest{}, err
+			}
+			pairs = append(pairs, p)
+		}
+		return hashDict(pairs), nil
+	case reflect.Struct:
+		return hashStruct(v, depth)
+	}
+	return Digest{}, fmt.Errorf("objecthash: unsupported type %s", v.Type())
+}
+
+func hashPair(k, v reflect.Value, depth int) ([]byte, error) {
+	kd, err := hashValue(k, depth+1)
+	if err != nil {
+		return nil, err
+	}
+	vd, err := hashValue(v, depth+1)
+	if err != nil {
+		return nil, err
+	}
+	return append(kd[:], vd[:]...), nil
+}
+
+// hashDict hashes key-value digest pairs in sorted order.
+func hashDict(pairs [][]byte) Digest {
+	sort.Slice(pairs, func(i, j int) bool { return bytes.Compare(pairs[i], pairs[j]) < 0 })
+	return sum('d', bytes.Join(pairs, nil))
+}
+
+func hashStruct(v reflect.Value, depth int) (Digest, error) {
+	t := v.Type()
+	var pairs [][]byte
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name, opts := f.Name, ""
+		if tag, ok := f.Tag.Lookup("objecthash"); ok {
+			if tag == "-" {
+				continue
+			}
+			if i := strings.IndexByte(tag, ','); i >= 0 {
+				tag, opts = tag[:i], tag[i+1:]
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fv := v.Field(i)
+		if opts == "omitempty" && fv.IsZero() {
+			continue
+		}
+		p, err := hashPair(reflect.ValueOf(name), fv, depth)
+		if err != nil {
+			return Digest{}, err
+		}
+		pairs = append(pairs, p)
+	}
+	return hashDict(pairs), nil
+}
@@ -0,0 +1,165 @@
+package objecthash
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ouzklcn/blake"
+)
+
+func mustSum(t *testing.T, v interface{}) Digest {
+	t.Helper()
+	d, err := Sum(v)
+	if err != nil {
+		t.Fatalf("Sum(%#v): %v", v, err)
+	}
+	return d
+}
+
+func TestScalars(t *testing.T) {
+	tagged := func(tag byte, s string) Digest {
+		return blake.Sum256(append([]byte{tag}, s...))
+	}
+	tests := []struct {
+		in   interface{}
+		want Digest
+	}{
+		{nil, tagged('n', "")},
+		{true, tagged('b', "1")},
+		{int8(-5), tagged('i', "-5")},
+		{uint64(5), tagged('i', "5")},
+		{1.5, tagged('f', "1.5")},
+		{"Golang", tagged('u', "Golang")},
+		{[]byte("Golang"), tagged('r', "Golang")},
+		{[2]byte{'G', 'o'}, tagged('r', "Go")},
+	}
+	for _, tt := range tests {
+		if got := mustSum(t, tt.in); got != tt.want {
+			t.Errorf("Sum(%#v) = %x, want %x", tt.in, got, tt.want)
+		}
+	}
+	type myByte uint8
+	if mustSum(t, []myByte{'G', 'o'}) != tagged('r', "Go") || mustSum(t, [2]myByte{'G', 'o'}) != tagged('r', "Go") {
+		t.Error("named byte types hash differently from []byte")
+	}
+	if mustSum(t, 5) != mustSum(t, uint16(5)) {
+		t.Error("integers of different types hash differently")
+	}
+	if mustSum(t, 5) == mustSum(t, 5.0) || mustSum(t, "5") == mustSum(t, 5) {
+		t.Error("values of different types hash alike")
+	}
+}
+
+func TestGolden(t *testing.T) {
+	v := map[string]interface{}{
+		"name":  "blake",
+		"sizes": []int{224, 256, 384, 512},
+		"salt":  nil,
+	}
+	want := "b0e81a3566e899e4f54730d31f7d9b9258bc48bae3ca19b41bfd4e3b573a6b12"
+	if got := fmt.Sprintf("%x", mustSum(t, v)); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+type configA struct {
+	Name    string
+	Port    int
+	Tags    []string
+	private int
+}
+
+type configB struct {
+	Tags []string
+	Port uint16
+	Name string
+}
+
+type tagged struct {
+	Name    string `objecthash:"name"`
+	Port    int    `objecthash:"Port,omitempty"`
+	Ignored string `objecthash:"-"`
+	Tags    []string
+}
+
+func TestStructs(t *testing.T) {
+	a := configA{Name: "x", Port: 80, Tags: []string{"a"}, private: 1}
+	b := configB{Tags: []string{"a"}, Port: 80, Name: "x"}
+	if mustSum(t, a) != mustSum(t, b) {
+		t.Error("field order, integer width or unexported fields change the digest")
+	}
+	m := map[string]interface{}{"Name": "x", "Port": 80, "Tags": []string{"a"}}
+	if mustSum(t, a) != mustSum(t, m) {
+		t.Error("struct differs from the equivalent map")
+	}
+	if mustSum(t, &a) != mustSum(t, a) {
+		t.Error("pointer differs from the value it refers to")
+	}
+
+	c := tagged{Name: "x", Ignored: "y", Tags: []string{"a"}}
+	want := mustSum(t, map[string]interface{}{"name": "x", "Tags": []string{"a"}})
+	if mustSum(t, c) != want {
+		t.Error("struct tags are not honored")
+	}
+	c.Port = 1
+	if mustSum(t, c) == want {
+		t.Error("non-zero omitempty field is omitted")
+	}
+}
+
+func TestAmbiguity(t *testing.T) {
+	values := []interface{}{
+		[]string{"ab", "c"},
+		[]string{"a", "bc"},
+		[]string{"abc"},
+		[][]string{{"abc"}},
+		[]string{},
+		[]string(nil),
+		map[string]string{},
+		"",
+	}
+	seen := make(map[Digest]int)
+	for i, v := range values {
+		d := mustSum(t, v)
+		if j, ok := seen[d]; ok {
+			t.Errorf("values %d and %d hash alike", j, i)
+		}
+		seen[d] = i
+	}
+}
+
+type custom struct{ id int }
+
+func (c custom) ObjectHash() (Digest, error) {
+	return blake.Sum256([]byte(fmt.Sprint("custom ", c.id))), nil
+}
+
+func TestHasherAndText(t *testing.T) {
+	if mustSum(t, []interface{}{custom{7}}) != mustSum(t, []interface{}{custom{7}}) {
+		t.Error("Hasher digest is not stable")
+	}
+	if got := mustSum(t, custom{7}); got != blake.Sum256([]byte("custom 7")) {
+		t.Error("Hasher digest is not used")
+	}
+	ts := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	if got := mustSum(t, ts); got != blake.Sum256([]byte("t2026-10-19T12:00:00Z")) {
+		t.Error("encoding.TextMarshaler is not used")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	type node struct{ Next *node }
+	n := &node{}
+	n.Next = n
+	if _, err := Sum(n); err == nil {
+		t.Error("expected error for cyclic value")
+	}
+	if _, err := Sum(make(chan int)); err == nil {
+		t.Error("expected error for channel")
+	}
+	if _, err := Sum(map[string]float64{"x": math.NaN()}); err == nil {
+		t.Error("expected error for NaN")
+	}
+}
@@ -0,0 +1,78 @@
+package blake
+
+import (
+	"hash"
+)
+
+// Prefix256 holds a BLAKE-256 state which has absorbed a fixed prefix.
+// Hashing a suffix starts from a copy of that state, so the prefix is
+// processed only once. A Prefix256 is never modified after creation
+// and is safe for concurrent use.
+type Prefix256 struct {
+	d digest256
+}
+
+// Prefix512 is the BLAKE-512 counterpart of Prefix256.
+type Prefix512 struct {
+	d digest512
+}
+
+// NewPrefix256 returns a Prefix256 for the given prefix.
+func NewPrefix256(prefix []byte) *Prefix256 {
+	p := new(Prefix256)
+	p.d.Reset()
+	p.d.Write(prefix)
+	return p
+}
+
+// NewPrefix512 returns a Prefix512 for the given prefix.
+func NewPrefix512(prefix []byte) *Prefix512 {
+	p := new(Prefix512)
+	p.d.Reset()
+	p.d.Write(prefix)
+	return p
+}
+
+// Sum returns the BLAKE-256 checksum of prefix || suffix without
+// allocating.
+func (p *Prefix256) Sum(suffix []byte) [Size256]byte {
+	d := p.d
+	d.Write(suffix)
+	return d.checkSum()
+}
+
+// Sum returns the BLAKE-512 checksum of prefix || suffix without
+// allocating.
+func (p *Prefix512) Sum(suffix []byte) [Size512]byte {
+	d := p.d
+	d.Write(suffix)
+	return d.checkSum()
+}
+
+// New returns a hash.Hash which has already absorbed the prefix.
+// Reset returns it to the state right after the prefix.
+func (p *Prefix256) New() hash.Hash {
+	return &prefixed256{p.d, p.d}
+}
+
+// New returns a hash.Hash which has already absorbed the prefix.
+// Reset returns it to the state right after the prefix.
+func (p *Prefix512) New() hash.Hash {
+	return &prefixed512{p.d, p.d}
+}
+
+// prefixed256 is a digest256 whose Reset restores the post-prefix state.
+type prefixed256 struct {
+	digest256
+	init digest256
+}
+
+func (d *prefixed256) Reset() { d.digest256 = d.init }
+
+// prefixed512 is a digest512 whose Reset restores the post-prefix state.
+type prefixed512 struct {
+	digest512
+	init digest512
+}
+
+func (d *prefixed512) Reset() { d.digest512 = d.init }
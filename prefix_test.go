@@ -0,0 +1,71 @@
+package blake
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestPrefix(t *testing.T) {
+	prefix := bytes.Repeat([]byte("domain/"), 30)
+	p256, p512 := NewPrefix256(prefix), NewPrefix512(prefix)
+	for _, suffix := range []string{"", "a", "Golang", string(bytes.Repeat([]byte{'x'}, 200))} {
+		in := append(append([]byte(nil), prefix...), suffix...)
+		if p256.Sum([]byte(suffix)) != Sum256(in) {
+			t.Errorf("Prefix256.Sum(%.10q) differs from Sum256", suffix)
+		}
+		if p512.Sum([]byte(suffix)) != Sum512(in) {
+			t.Errorf("Prefix512.Sum(%.10q) differs from Sum512", suffix)
+		}
+		h := p256.New()
+		h.Write([]byte(suffix))
+		if want := Sum256(in); !bytes.Equal(h.Sum(nil), want[:]) {
+			t.Errorf("Prefix256.New() hash of %.10q differs from Sum256", suffix)
+		}
+		h = p512.New()
+		h.Write([]byte(suffix))
+		if want := Sum512(in); !bytes.Equal(h.Sum(nil), want[:]) {
+			t.Errorf("Prefix512.New() hash of %.10q differs from Sum512", suffix)
+		}
+		h.Reset()
+		h.Write([]byte(suffix))
+		if want := Sum512(in); !bytes.Equal(h.Sum(nil), want[:]) {
+			t.Errorf("Prefix512.New() hash of %.10q after Reset differs from Sum512", suffix)
+		}
+	}
+
+	suffix := []byte("suffix")
+	h := p256.New()
+	h.Write([]byte("discarded"))
+	h.Reset()
+	h.Write(suffix)
+	if want := Sum256(append(append([]byte(nil), prefix...), suffix...)); !bytes.Equal(h.Sum(nil), want[:]) {
+		t.Error("Prefix256.New() hash lost the prefix after Reset")
+	}
+
+	if n := testing.AllocsPerRun(10, func() { p256.Sum(suffix) }); n != 0 {
+		t.Errorf("Prefix256.Sum allocates %v times", n)
+	}
+	if n := testing.AllocsPerRun(10, func() { p512.Sum(suffix) }); n != 0 {
+		t.Errorf("Prefix512.Sum allocates %v times", n)
+	}
+}
+
+func TestPrefixConcurrent(t *testing.T) {
+	p := NewPrefix256([]byte("shared header"))
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				s := []byte(fmt.Sprintf("%d/%d", g, i))
+				if p.Sum(s) != Sum256(append([]byte("shared header"), s...)) {
+					t.Errorf("wrong checksum for %s", s)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
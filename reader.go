@@ -0,0 +1,160 @@
+package blake
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// readBufSize is the size of the pooled read buffers. It is a multiple
+// of both block sizes, so full buffers are hashed without copying into
+// the partial block.
+const readBufSize = 512 * BlockSize512
+
+var readBufPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, readBufSize)
+		return &b
+	},
+}
+
+// ReadError records a failed read while hashing a stream.
+type ReadError struct {
+	Offset int64 // number of bytes hashed before the failure
+	Err    error
+}
+
+func (e *ReadError) Error() string {
+	return fmt.Sprintf("blake: read at offset %d: %v", e.Offset, e.Err)
+}
+
+func (e *ReadError) Unwrap() error { return e.Err }
+
+// readFrom writes r to w until EOF using a pooled buffer, checking ctx
+// before every read. Read failures, including io.ErrUnexpectedEOF from
+// the underlying reader, are returned as *ReadError.
+func readFrom(ctx context.Context, w io.Writer, r io.Reader) (n int64, err error) {
+	bp := readBufPool.Get().(*[]byte)
+	defer readBufPool.Put(bp)
+	buf := *bp
+	for {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		m, err := r.Read(buf)
+		if m > 0 {
+			w.Write(buf[:m])
+			n += int64(m)
+		}
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, &ReadError{Offset: n, Err: err}
+		}
+	}
+}
+
+// ReadFrom implements io.ReaderFrom, so io.Copy into the hash reads
+// with a pooled, block-aligned buffer.
+func (d *digest256) ReadFrom(r io.Reader) (int64, error) {
+	return readFrom(context.Background(), d, r)
+}
+
+// ReadFrom implements io.ReaderFrom, so io.Copy into the hash reads
+// with a pooled, block-aligned buffer.
+func (d *digest512) ReadFrom(r io.Reader) (int64, error) {
+	return readFrom(context.Background(), d, r)
+}
+
+// SumReader224 returns the BLAKE-224 checksum of r read until EOF.
+// It stops with ctx.Err() if ctx is done before the stream ends.
+func SumReader224(ctx context.Context, r io.Reader) (sum224 [Size224]byte, err error) {
+	d := new(digest256)
+	d.is224 = true
+	d.Reset()
+	if _, err = readFrom(ctx, d, r); err != nil {
+		return
+	}
+	sum := d.checkSum()
+	copy(sum224[:], sum[:Size224])
+	return
+}
+
+// SumReader256 returns the BLAKE-256 checksum of r read until EOF.
+// It stops with ctx.Err() if ctx is done before the stream ends.
+func SumReader256(ctx context.Context, r io.Reader) ([Size256]byte, error) {
+	d := new(digest256)
+	d.Reset()
+	if _, err := readFrom(ctx, d, r); err != nil {
+		return [Size256]byte{}, err
+	}
+	return d.checkSum(), nil
+}
+
+// SumReader384 returns the BLAKE-384 checksum of r read until EOF.
+// It stops with ctx.Err() if ctx is done before the stream ends.
+func SumReader384(ctx context.Context, r io.Reader) (sum384 [Size384]byte, err error) {
+	d := new(digest512)
+	d.is384 = true
+	d.Reset()
+	if _, err = readFrom(ctx, d, r); err != nil {
+		return
+	}
+	sum := d.checkSum()
+	copy(sum384[:], sum[:Size384])
+	return
+}
+
+// SumReader512 returns the BLAKE-512 checksum of r read until EOF.
+// It stops with ctx.Err() if ctx is done before the stream ends.
+func SumReader512(ctx context.Context, r io.Reader) ([Size512]byte, error) {
+	d := new(digest512)
+	d.Reset()
+	if _, err := readFrom(ctx, d, r); err != nil {
+		return [Size512]byte{}, err
+	}
+	return d.checkSum(), nil
+}
+
+// SumFile224 returns the BLAKE-224 checksum of the named file.
+func SumFile224(ctx context.Context, name string) ([Size224]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return [Size224]byte{}, err
+	}
+	defer f.Close()
+	return SumReader224(ctx, f)
+}
+
+// SumFile256 returns the BLAKE-256 checksum of the named file.
+func SumFile256(ctx context.Context, name string) ([Size256]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return [Size256]byte{}, err
+	}
+	defer f.Close()
+	return SumReader256(ctx, f)
+}
+
+// SumFile384 returns the BLAKE-384 checksum of the named file.
+func SumFile384(ctx context.Context, name string) ([Size384]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return [Size384]byte{}, err
+	}
+	defer f.Close()
+	return SumReader384(ctx, f)
+}
+
+// SumFile512 returns the BLAKE-512 checksum of the named file.
+func SumFile512(ctx context.Context, name string) ([Size512]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return [Size512]byte{}, err
+	}
+	defer f.Close()
+	return SumReader512(ctx, f)
+}
@@ -0,0 +1,137 @@
+package blake
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSumReader(t *testing.T) {
+	ctx := context.Background()
+	for i, v := range vectors224 {
+		if res, _ := SumReader224(ctx, strings.NewReader(v.in)); fmt.Sprintf("%x", res) != v.out {
+			t.Errorf("224 %d: expected %q, got %x", i, v.out, res)
+		}
+	}
+	for i, v := range vectors256 {
+		if res, _ := SumReader256(ctx, strings.NewReader(v.in)); fmt.Sprintf("%x", res) != v.out {
+			t.Errorf("256 %d: expected %q, got %x", i, v.out, res)
+		}
+	}
+	for i, v := range vectors384 {
+		if res, _ := SumReader384(ctx, strings.NewReader(v.in)); fmt.Sprintf("%x", res) != v.out {
+			t.Errorf("384 %d: expected %q, got %x", i, v.out, res)
+		}
+	}
+	for i, v := range vectors512 {
+		if res, _ := SumReader512(ctx, strings.NewReader(v.in)); fmt.Sprintf("%x", res) != v.out {
+			t.Errorf("512 %d: expected %q, got %x", i, v.out, res)
+		}
+	}
+
+	// Inputs spanning several buffers.
+	data := bytes.Repeat([]byte("0123456789"), readBufSize/4)
+	if res, _ := SumReader256(ctx, bytes.NewReader(data)); res != Sum256(data) {
+		t.Error("SumReader256 differs from Sum256 for a large input")
+	}
+	if res, _ := SumReader512(ctx, bytes.NewReader(data)); res != Sum512(data) {
+		t.Error("SumReader512 differs from Sum512 for a large input")
+	}
+}
+
+func TestReadFrom(t *testing.T) {
+	data := bytes.Repeat([]byte("Golang"), 50000)
+	for _, h := range []io.Writer{New224(), New256(), New384(), New512()} {
+		if _, ok := h.(io.ReaderFrom); !ok {
+			t.Fatalf("%T does not implement io.ReaderFrom", h)
+		}
+	}
+	h := New256()
+	if n, err := io.Copy(h, struct{ io.Reader }{bytes.NewReader(data)}); err != nil || n != int64(len(data)) {
+		t.Fatalf("io.Copy = %d, %v", n, err)
+	}
+	if sum := Sum256(data); !bytes.Equal(h.Sum(nil), sum[:]) {
+		t.Error("checksum after io.Copy differs from Sum256")
+	}
+}
+
+type failingReader struct {
+	n int
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.n == 0 {
+		return 0, errors.New("disk on fire")
+	}
+	if len(p) > r.n {
+		p = p[:r.n]
+	}
+	r.n -= len(p)
+	return len(p), nil
+}
+
+func TestSumReaderErrors(t *testing.T) {
+	_, err := SumReader256(context.Background(), &failingReader{n: 100000})
+	var re *ReadError
+	if !errors.As(err, &re) || re.Offset != 100000 {
+		t.Errorf("expected ReadError at offset 100000, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := SumReader512(ctx, strings.NewReader("data")); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestTruncatedStream(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(bytes.Repeat([]byte("BLAKE"), 100000))
+	zw.Close()
+	truncated := buf.Bytes()[:buf.Len()/2]
+
+	zr, err := gzip.NewReader(bytes.NewReader(truncated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(New256(), zr); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("io.Copy: expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	zr, _ = gzip.NewReader(bytes.NewReader(truncated))
+	_, err = SumReader256(context.Background(), zr)
+	var re *ReadError
+	if !errors.As(err, &re) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("SumReader256: expected ReadError wrapping io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestSumFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "blake")
+	if err := os.WriteFile(name, []byte("BLAKE"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if res, err := SumFile224(ctx, name); err != nil || res != Sum224([]byte("BLAKE")) {
+		t.Errorf("SumFile224 = %x, %v", res, err)
+	}
+	if res, err := SumFile256(ctx, name); err != nil || res != Sum256([]byte("BLAKE")) {
+		t.Errorf("SumFile256 = %x, %v", res, err)
+	}
+	if res, err := SumFile384(ctx, name); err != nil || res != Sum384([]byte("BLAKE")) {
+		t.Errorf("SumFile384 = %x, %v", res, err)
+	}
+	if res, err := SumFile512(ctx, name); err != nil || res != Sum512([]byte("BLAKE")) {
+		t.Errorf("SumFile512 = %x, %v", res, err)
+	}
+	if _, err := SumFile256(ctx, name+".missing"); !os.IsNotExist(err) {
+		t.Errorf("expected not-exist error, got %v", err)
+	}
+}
@@ -0,0 +1,288 @@
+package rsync
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/ouzklcn/blake"
+)
+
+// maxLiteral bounds the size of a single literal operation.
+const maxLiteral = 64 << 10
+
+// ErrChecksum is returned by Patch when the rebuilt file does not match
+// the whole-file checksum of the delta.
+var ErrChecksum = errors.New("rsync: rebuilt file does not match its checksum")
+
+// Op is a delta operation. A copy operation has Count > 0 and copies
+// Count blocks starting at block Start of the basis. A literal
+// operation has Count == 0 and inserts Data.
+type Op struct {
+	Start, Count int
+	Data         []byte
+}
+
+// Delta rebuilds a new file from a basis.
+type Delta struct {
+	BlockSize int                  // block size of the signature
+	Size      int64                // size of the new file
+	Sum       blake.Digest256Value // BLAKE-256 checksum of the new file
+	Ops       []Op
+}
+
+type deltaBuilder struct {
+	d *Delta
+}
+
+func (db *deltaBuilder) copyBlock(i int) {
+	ops := db.d.Ops
+	if n := len(ops); n > 0 && ops[n-1].Count > 0 && ops[n-1].Start+ops[n-1].Count == i {
+		ops[n-1].Count++
+		return
+	}
+	db.d.Ops = append(ops, Op{Start: i, Count: 1})
+}
+
+func (db *deltaBuilder) literal(p []byte) {
+	if len(p) > 0 {
+		db.d.Ops = append(db.d.Ops, Op{Data: append([]byte(nil), p...)})
+	}
+}
+
+// NewDelta computes the delta of the new file read from r against the
+// signature of a basis.
+func NewDelta(sig *Signature, r io.Reader) (*Delta, error) {
+	bs := sig.BlockSize
+	// Only full blocks are matched while rolling. The last block of
+	// the basis, which may be short, is only matched at the end.
+	table := make(map[uint32][]int)
+	for i, blk := range sig.Blocks {
+		if sig.blockLen(i) == bs {
+			table[blk.Weak] = append(table[blk.Weak], i)
+		}
+	}
+	match := func(w uint32, cand []int, p []byte) int {
+		var strong []byte
+		for _, i := range cand {
+			if sig.Blocks[i].Weak != w {
+				continue
+			}
+			if strong == nil {
+				strong = strongSum(p, sig.StrongLen)
+			}
+			if bytes.Equal(strong, sig.Blocks[i].Strong) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	h := blake.New256()
+	in := &window{r: io.TeeReader(r, h)}
+	d := &Delta{BlockSize: bs}
+	db := &deltaBuilder{d}
+	var a, b uint32
+	rolling := false
+	for {
+		if err := in.ensure(bs + 1); err != nil {
+			return nil, err
+		}
+		avail := len(in.buf) - in.pos
+		if avail < bs {
+			// Try the last basis block against the tail.
+			if last := len(sig.Blocks) - 1; avail > 0 && last >= 0 && sig.blockLen(last) == avail {
+				tail := in.buf[in.pos:]
+				if match(weak(weakSum(tail)), []int{last}, tail) == last {
+					db.literal(in.buf[in.lit:in.pos])
+					db.copyBlock(last)
+					in.pos = len(in.buf)
+					in.lit = in.pos
+				}
+			}
+			break
+		}
+		blk := in.buf[in.pos : in.pos+bs]
+		if !rolling {
+			a, b = weakSum(blk)
+			rolling = true
+		}
+		w := weak(a, b)
+		if cand, ok := table[w]; ok {
+			if i := match(w, cand, blk); i >= 0 {
+				db.literal(in.buf[in.lit:in.pos])
+				db.copyBlock(i)
+				in.pos += bs
+				in.lit = in.pos
+				rolling = false
+				continue
+			}
+		}
+		if avail > bs {
+			out, next := uint32(in.buf[in.pos]), uint32(in.buf[in.pos+bs])
+			a += next - out
+			b += a - uint32(bs)*out
+		} else {
+			rolling = false
+		}
+		in.pos++
+		if in.pos-in.lit >= maxLiteral {
+			db.literal(in.buf[in.lit:in.pos])
+			in.lit = in.pos
+		}
+	}
+	db.literal(in.buf[in.lit:])
+	d.Size = in.off + int64(len(in.buf))
+	h.Sum(d.Sum[:0])
+	return d, nil
+}
+
+// window buffers the new file from the start of the pending literal.
+type window struct {
+	r   io.Reader
+	buf []byte
+	off int64 // stream offset of buf[0]
+	lit int   // start of the pending literal
+	pos int   // start of the current block
+	eof bool
+}
+
+// ensure reads until n bytes are buffered from pos or the stream ends,
+// discarding data before lit.
+func (w *window) ensure(n int) error {
+	if w.eof || len(w.buf)-w.pos >= n {
+		return nil
+	}
+	if w.lit > 0 {
+		w.off += int64(w.lit)
+		w.buf = w.buf[:copy(w.buf, w.buf[w.lit:])]
+		w.pos -= w.lit
+		w.lit = 0
+	}
+	if need := w.pos + n + maxLiteral; cap(w.buf) < need {
+		buf := make([]byte, len(w.buf), need)
+		copy(buf, w.buf)
+		w.buf = buf
+	}
+	for len(w.buf)-w.pos < n {
+		m, err := w.r.Read(w.buf[len(w.buf):cap(w.buf)])
+		w.buf = w.buf[:len(w.buf)+m]
+		if err == io.EOF {
+			w.eof = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Patch writes the file described by d to w, copying blocks from basis.
+// It returns ErrChecksum if the result does not match d.Sum, in which
+// case the data written to w must be discarded.
+func Patch(basis io.ReaderAt, d *Delta, w io.Writer) error {
+	if d.BlockSize <= 0 {
+		return ErrParams
+	}
+	h := blake.New256()
+	out := io.MultiWriter(w, h)
+	var size int64
+	buf := make([]byte, d.BlockSize)
+	for _, op := range d.Ops {
+		if op.Count == 0 {
+			if _, err := out.Write(op.Data); err != nil {
+				return err
+			}
+			size += int64(len(op.Data))
+			continue
+		}
+		for i := op.Start; i < op.Start+op.Count; i++ {
+			n, err := basis.ReadAt(buf, int64(i)*int64(d.BlockSize))
+			if err != nil && !(err == io.EOF && n > 0) {
+				return err
+			}
+			if _, err := out.Write(buf[:n]); err != nil {
+				return err
+			}
+			size += int64(n)
+		}
+	}
+	var sum blake.Digest256Value
+	h.Sum(sum[:0])
+	if size != d.Size || !sum.Equal(d.Sum) {
+		return ErrChecksum
+	}
+	return nil
+}
+
+var deltaMagic = []byte("BLKD")
+
+// MarshalBinary encodes d as
+//
+//	"BLKD" || uint32(BlockSize) || uint64(Size) || Sum || ops
+//
+// where a copy operation is 'C' || uvarint(Start) || uvarint(Count) and
+// a literal is 'L' || uvarint(len(Data)) || Data.
+func (d *Delta) MarshalBinary() ([]byte, error) {
+	b := append([]byte(nil), deltaMagic...)
+	b = binary.BigEndian.AppendUint32(b, uint32(d.BlockSize))
+	b = binary.BigEndian.AppendUint64(b, uint64(d.Size))
+	b = append(b, d.Sum[:]...)
+	for _, op := range d.Ops {
+		if op.Count > 0 {
+			b = append(b, 'C')
+			b = binary.AppendUvarint(b, uint64(op.Start))
+			b = binary.AppendUvarint(b, uint64(op.Count))
+		} else {
+			b = append(b, 'L')
+			b = binary.AppendUvarint(b, uint64(len(op.Data)))
+			b = append(b, op.Data...)
+		}
+	}
+	return b, nil
+}
+
+// UnmarshalBinary decodes a delta encoded by MarshalBinary.
+func (d *Delta) UnmarshalBinary(b []byte) error {
+	const header = 4 + 4 + 8 + blake.Size256
+	if len(b) < header || string(b[:4]) != string(deltaMagic) {
+		return ErrFormat
+	}
+	var nd Delta
+	nd.BlockSize = int(binary.BigEndian.Uint32(b[4:]))
+	nd.Size = int64(binary.BigEndian.Uint64(b[8:]))
+	copy(nd.Sum[:], b[16:header])
+	if nd.BlockSize <= 0 || nd.Size < 0 {
+		return ErrFormat
+	}
+	b = b[header:]
+	for len(b) > 0 {
+		kind := b[0]
+		x, n := binary.Uvarint(b[1:])
+		if n <= 0 {
+			return ErrFormat
+		}
+		b = b[1+n:]
+		switch kind {
+		case 'C':
+			y, m := binary.Uvarint(b)
+			if m <= 0 || y == 0 || x > 1<<31 || y > 1<<31 {
+				return ErrFormat
+			}
+			b = b[m:]
+			nd.Ops = append(nd.Ops, Op{Start: int(x), Count: int(y)})
+		case 'L':
+			if x == 0 || x > uint64(len(b)) {
+				return ErrFormat
+			}
+			nd.Ops = append(nd.Ops, Op{Data: append([]byte(nil), b[:x]...)})
+			b = b[x:]
+		default:
+			return ErrFormat
+		}
+	}
+	*d = nd
+	return nil
+}
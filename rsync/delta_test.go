@@ -0,0 +1,101 @@
+package rsync
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"testing/iotest"
+)
+
+func random(n int, seed int64) []byte {
+	b := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(b)
+	return b
+}
+
+func roundTrip(t *testing.T, basis, target []byte, bs int) *Delta {
+	t.Helper()
+	sig, err := NewSignature(bytes.NewReader(basis), bs, DefaultStrongLen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := NewDelta(sig, iotest.HalfReader(bytes.NewReader(target)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, _ := d.MarshalBinary()
+	var dec Delta
+	if err := dec.UnmarshalBinary(enc); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := Patch(bytes.NewReader(basis), &dec, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), target) {
+		t.Fatalf("patched file differs from the target")
+	}
+	return d
+}
+
+func literalBytes(d *Delta) int {
+	n := 0
+	for _, op := range d.Ops {
+		n += len(op.Data)
+	}
+	return n
+}
+
+func TestDelta(t *testing.T) {
+	basis := random(200<<10, 1)
+	edited := append([]byte(nil), basis[:50000]...)
+	edited = append(edited, "inserted BLAKE bytes"...)
+	edited = append(edited, basis[50100:]...)
+	d := roundTrip(t, basis, edited, DefaultBlockSize)
+	if lit := literalBytes(d); lit > 3*DefaultBlockSize {
+		t.Errorf("small edit sent %d literal bytes", lit)
+	}
+
+	if d := roundTrip(t, basis, basis, DefaultBlockSize); len(d.Ops) != 1 || literalBytes(d) != 0 {
+		t.Errorf("identical file produced %d ops", len(d.Ops))
+	}
+	other := random(300<<10, 2)
+	if d := roundTrip(t, basis, other, DefaultBlockSize); literalBytes(d) != len(other) {
+		t.Errorf("unrelated file copied %d bytes from the basis", len(other)-literalBytes(d))
+	}
+
+	// The short last block of the basis is matched at the end.
+	short := basis[:10*DefaultBlockSize+100]
+	if d := roundTrip(t, short, append([]byte("x"), short...), DefaultBlockSize); literalBytes(d) != 1 {
+		t.Errorf("expected 1 literal byte, got %d", literalBytes(d))
+	}
+
+	roundTrip(t, nil, []byte("Golang"), 4)
+	roundTrip(t, []byte("Golang"), nil, 4)
+	roundTrip(t, []byte("ube"), []byte("ube"), 4)
+}
+
+func TestPatchChecksum(t *testing.T) {
+	basis := random(64<<10, 3)
+	sig, _ := NewSignature(bytes.NewReader(basis), 1024, 8)
+	d, err := NewDelta(sig, bytes.NewReader(basis))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := append([]byte(nil), basis...)
+	changed[5000] ^= 1
+	if err := Patch(bytes.NewReader(changed), d, new(bytes.Buffer)); err != ErrChecksum {
+		t.Errorf("expected ErrChecksum for a changed basis, got %v", err)
+	}
+	if err := Patch(bytes.NewReader(basis[:60<<10]), d, new(bytes.Buffer)); err == nil {
+		t.Error("expected error for a truncated basis")
+	}
+
+	enc, _ := d.MarshalBinary()
+	var dec Delta
+	for _, bad := range [][]byte{enc[:10], append(enc[:len(enc):len(enc)], 'L', 5, 'x'), append(enc[:len(enc):len(enc)], 'Q')} {
+		if err := dec.UnmarshalBinary(bad); err != ErrFormat {
+			t.Errorf("expected ErrFormat, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Package rsync implements the rsync algorithm with BLAKE-256 strong
+// checksums.
+//
+// The receiver computes a Signature of its basis file: for every block,
+// a rolling weak checksum and a BLAKE-256 checksum truncated to
+// StrongLen bytes. The sender computes a Delta of the new file against
+// the signature, consisting of references to basis blocks and literal
+// data, along with the BLAKE-256 checksum of the whole new file. The
+// receiver applies the delta with Patch, which rebuilds the new file
+// from the basis and checks it against the whole-file checksum, so a
+// false block match can never go unnoticed.
+package rsync
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/ouzklcn/blake"
+)
+
+// Default parameters of NewSignature.
+const (
+	DefaultBlockSize = 2048
+	DefaultStrongLen = 16
+)
+
+var (
+	// ErrFormat is returned when decoding malformed signatures or
+	// deltas.
+	ErrFormat = errors.New("rsync: invalid encoding")
+	// ErrParams is returned for invalid block sizes or strong checksum
+	// lengths.
+	ErrParams = errors.New("rsync: invalid signature parameters")
+)
+
+// BlockSig is the signature of a basis block.
+type BlockSig struct {
+	Weak   uint32
+	Strong []byte
+}
+
+// Signature is the signature of a basis file.
+type Signature struct {
+	BlockSize int   // size of all blocks but the last
+	StrongLen int   // length of the truncated strong checksums
+	Size      int64 // size of the basis file
+	Blocks    []BlockSig
+}
+
+// weakSum returns the rsync weak checksum of a block:
+//
+//	a = sum(x[i]) mod 2^16
+//	b = sum((len(x)-i) * x[i]) mod 2^16
+//	weak = a | b<<16
+func weakSum(p []byte) (a, b uint32) {
+	n := uint32(len(p))
+	for i, x := range p {
+		a += uint32(x)
+		b += (n - uint32(i)) * uint32(x)
+	}
+	return a, b
+}
+
+func weak(a, b uint32) uint32 {
+	return a&0xffff | b<<16
+}
+
+// strongSum returns the truncated strong checksum of a block.
+func strongSum(p []byte, n int) []byte {
+	sum := blake.Sum256(p)
+	return sum[:n]
+}
+
+// NewSignature computes the signature of the basis read from r with the
+// given block size and strong checksum length, which must be between 4
+// and 32 bytes.
+func NewSignature(r io.Reader, blockSize, strongLen int) (*Signature, error) {
+	if blockSize <= 0 || strongLen < 4 || strongLen > blake.Size256 {
+		return nil, ErrParams
+	}
+	sig := &Signature{BlockSize: blockSize, StrongLen: strongLen}
+	buf := make([]byte, blockSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			sig.Size += int64(n)
+			sig.Blocks = append(sig.Blocks, BlockSig{weak(weakSum(buf[:n])), strongSum(buf[:n], strongLen)})
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return sig, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// blockLen returns the length of block i.
+func (sig *Signature) blockLen(i int) int {
+	if i == len(sig.Blocks)-1 {
+		return int(sig.Size - int64(i)*int64(sig.BlockSize))
+	}
+	return sig.BlockSize
+}
+
+var sigMagic = []byte("BLKS")
+
+// MarshalBinary encodes sig as
+//
+//	"BLKS" || uint32(BlockSize) || uint8(StrongLen) || uint64(Size) ||
+//	for each block: uint32(Weak) || Strong
+//
+// with big-endian integers.
+func (sig *Signature) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, 17+len(sig.Blocks)*(4+sig.StrongLen))
+	b = append(b, sigMagic...)
+	b = binary.BigEndian.AppendUint32(b, uint32(sig.BlockSize))
+	b = append(b, byte(sig.StrongLen))
+	b = binary.BigEndian.AppendUint64(b, uint64(sig.Size))
+	for _, blk := range sig.Blocks {
+		b = binary.BigEndian.AppendUint32(b, blk.Weak)
+		b = append(b, blk.Strong...)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary decodes a signature encoded by MarshalBinary.
+func (sig *Signature) UnmarshalBinary(b []byte) error {
+	if len(b) < 17 || string(b[:4]) != string(sigMagic) {
+		return ErrFormat
+	}
+	s := Signature{
+		BlockSize: int(binary.BigEndian.Uint32(b[4:])),
+		StrongLen: int(b[8]),
+		Size:      int64(binary.BigEndian.Uint64(b[9:])),
+	}
+	if s.BlockSize <= 0 || s.StrongLen < 4 || s.StrongLen > blake.Size256 ||
+		s.Size < 0 || s.Size > math.MaxInt64-int64(s.BlockSize)+1 {
+		return ErrFormat
+	}
+	b = b[17:]
+	// Compare block counts rather than byte lengths, which could
+	// overflow for a forged size.
+	n := (s.Size + int64(s.BlockSize) - 1) / int64(s.BlockSize)
+	if len(b)%(4+s.StrongLen) != 0 || n != int64(len(b)/(4+s.StrongLen)) {
+		return ErrFormat
+	}
+	s.Blocks = make([]BlockSig, n)
+	for i := range s.Blocks {
+		s.Blocks[i].Weak = binary.BigEndian.Uint32(b)
+		s.Blocks[i].Strong = append([]byte(nil), b[4:4+s.StrongLen]...)
+		b = b[4+s.StrongLen:]
+	}
+	*sig = s
+	return nil
+}
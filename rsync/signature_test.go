@@ -0,0 +1,66 @@
+package rsync
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestWeakRolling(t *testing.T) {
+	data := []byte("The quick brown fox jumps over the lazy dog, BLAKE!")
+	const n = 16
+	a, b := weakSum(data[:n])
+	for i := 1; i+n <= len(data); i++ {
+		out, in := uint32(data[i-1]), uint32(data[i+n-1])
+		a += in - out
+		b += a - n*out
+		wa, wb := weakSum(data[i : i+n])
+		if weak(a, b) != weak(wa, wb) {
+			t.Fatalf("%d: rolled checksum %08x, want %08x", i, weak(a, b), weak(wa, wb))
+		}
+	}
+}
+
+func TestSignature(t *testing.T) {
+	sig, err := NewSignature(strings.NewReader("BLAKE-256 block signature"), 8, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig.Size != 25 || len(sig.Blocks) != 4 || sig.blockLen(3) != 1 {
+		t.Fatalf("unexpected signature %+v", sig)
+	}
+	if !bytes.Equal(sig.Blocks[0].Strong, strongSum([]byte("BLAKE-25"), 8)) {
+		t.Error("strong checksum of the first block is wrong")
+	}
+
+	b, err := sig.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dec Signature
+	if err := dec.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if dec.BlockSize != 8 || dec.StrongLen != 8 || dec.Size != 25 || len(dec.Blocks) != 4 ||
+		dec.Blocks[3].Weak != sig.Blocks[3].Weak || !bytes.Equal(dec.Blocks[3].Strong, sig.Blocks[3].Strong) {
+		t.Errorf("decoded signature %+v differs from %+v", dec, sig)
+	}
+	// A size whose byte length overflows to that of a single block.
+	forged := []byte("BLKS\x00\x00\x00\x01\x20")
+	forged = binary.BigEndian.AppendUint64(forged, 1024819115206086201)
+	forged = append(forged, 0, 0, 0, 0)
+	huge := append(append([]byte(nil), b[:9]...), 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+	for _, bad := range [][]byte{nil, b[:len(b)-1], append([]byte("XXXX"), b[4:]...), forged, huge} {
+		if err := dec.UnmarshalBinary(bad); err != ErrFormat {
+			t.Errorf("expected ErrFormat, got %v", err)
+		}
+	}
+
+	if _, err := NewSignature(strings.NewReader(""), 0, 8); err != ErrParams {
+		t.Errorf("expected ErrParams, got %v", err)
+	}
+	if _, err := NewSignature(strings.NewReader(""), 8, 33); err != ErrParams {
+		t.Errorf("expected ErrParams, got %v", err)
+	}
+}
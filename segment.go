@@ -0,0 +1,105 @@
+package blake
+
+import "errors"
+
+var errSegmentWriterClosed = errors.New("blake: write to closed SegmentWriter")
+
+// SegmentWriter computes a BLAKE-256 checksum for every segment of a
+// fixed size of the data written to it, along with the checksum of the
+// whole stream. Data is absorbed directly into the two running states,
+// so nothing is buffered beyond a single partial block.
+type SegmentWriter struct {
+	full, seg digest256
+	size      int64
+	n         int64 // bytes written to the current segment
+	index     int64
+	fn        func(index int64, sum [Size256]byte)
+	sums      [][Size256]byte
+	closed    bool
+}
+
+// NewSegmentWriter returns a SegmentWriter with segments of size
+// bytes. If fn is not nil it is called with the index and checksum of
+// every segment as soon as it is complete; otherwise the checksums are
+// collected and returned by Segments. The last segment may be shorter
+// and is only emitted by Close.
+func NewSegmentWriter(size int64, fn func(index int64, sum [Size256]byte)) *SegmentWriter {
+	if size <= 0 {
+		panic("blake: segment size must be positive")
+	}
+	w := &SegmentWriter{size: size, fn: fn}
+	w.full.Reset()
+	w.seg.Reset()
+	return w
+}
+
+func (w *SegmentWriter) Write(p []byte) (nn int, err error) {
+	if w.closed {
+		return 0, errSegmentWriterClosed
+	}
+	nn = len(p)
+	for len(p) > 0 {
+		n := w.size - w.n
+		if int64(len(p)) < n {
+			n = int64(len(p))
+		}
+		w.full.Write(p[:n])
+		w.seg.Write(p[:n])
+		w.n += n
+		p = p[n:]
+		if w.n == w.size {
+			w.emit()
+		}
+	}
+	return
+}
+
+func (w *SegmentWriter) emit() {
+	sum := w.seg.checkSum()
+	if w.fn != nil {
+		w.fn(w.index, sum)
+	} else {
+		w.sums = append(w.sums, sum)
+	}
+	w.index++
+	w.n = 0
+	w.seg.Reset()
+}
+
+// Close emits the checksum of the last, partial segment if there is
+// one. An empty stream has no segments.
+func (w *SegmentWriter) Close() error {
+	if !w.closed {
+		w.closed = true
+		if w.n > 0 {
+			w.emit()
+		}
+	}
+	return nil
+}
+
+// Segments returns the checksums of the segments emitted so far when
+// no callback was given.
+func (w *SegmentWriter) Segments() [][Size256]byte { return w.sums }
+
+// Sum returns the BLAKE-256 checksum of all data written so far.
+func (w *SegmentWriter) Sum() [Size256]byte {
+	d := w.full
+	return d.checkSum()
+}
+
+// DiffSegments returns the indexes of the segments whose checksums
+// differ between want and got, including segments missing from either.
+func DiffSegments(want, got [][Size256]byte) []int64 {
+	var diff []int64
+	n := len(want)
+	if len(got) > n {
+		n = len(got)
+	}
+	for i := 0; i < n; i++ {
+		if i >= len(want) || i >= len(got) || want[i] != got[i] {
+			diff = append(diff, int64(i))
+		}
+	}
+	return diff
+}
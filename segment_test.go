@@ -0,0 +1,68 @@
+package blake
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestSegmentWriter(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	w := NewSegmentWriter(300, nil)
+	for p := data; len(p) > 0; {
+		k := 77
+		if k > len(p) {
+			k = len(p)
+		}
+		w.Write(p[:k])
+		p = p[k:]
+	}
+	if got := len(w.Segments()); got != 3 {
+		t.Errorf("expected 3 segments before Close, got %d", got)
+	}
+	w.Close()
+	want := [][Size256]byte{
+		Sum256(data[:300]), Sum256(data[300:600]), Sum256(data[600:900]), Sum256(data[900:]),
+	}
+	if segs := w.Segments(); fmt.Sprint(segs) != fmt.Sprint(want) {
+		t.Errorf("unexpected segments")
+	}
+	if w.Sum() != Sum256(data) {
+		t.Error("whole-stream checksum differs from Sum256")
+	}
+	if _, err := w.Write([]byte("x")); err == nil {
+		t.Error("expected error writing after Close")
+	}
+}
+
+func TestSegmentWriterCallback(t *testing.T) {
+	var got []int64
+	w := NewSegmentWriter(4, func(i int64, sum [Size256]byte) {
+		got = append(got, i)
+	})
+	w.Write(bytes.Repeat([]byte("a"), 8))
+	w.Close()
+	if fmt.Sprint(got) != "[0 1]" {
+		t.Errorf("unexpected callbacks %v", got)
+	}
+	if len(w.Segments()) != 0 {
+		t.Error("segments collected although a callback was given")
+	}
+
+	empty := NewSegmentWriter(4, nil)
+	empty.Close()
+	if len(empty.Segments()) != 0 || empty.Sum() != Sum256(nil) {
+		t.Error("empty stream has segments or a wrong checksum")
+	}
+}
+
+func TestDiffSegments(t *testing.T) {
+	a := [][Size256]byte{Sum256([]byte("a")), Sum256([]byte("b")), Sum256([]byte("c"))}
+	b := [][Size256]byte{a[0], Sum256([]byte("x")), a[2], a[2]}
+	if got := fmt.Sprint(DiffSegments(a, b)); got != "[1 3]" {
+		t.Errorf("DiffSegments = %s, want [1 3]", got)
+	}
+}
@@ -0,0 +1,337 @@
+package blake
+
+import (
+	"errors"
+	"math/bits"
+	"sort"
+	"sync"
+)
+
+// SparseDepth is the depth of a SparseMerkleTree. Every key is mapped
+// to a leaf by its BLAKE-256 checksum.
+const SparseDepth = 8 * Size256
+
+// ErrNodeNotFound is returned by a NodeStore when it has no node
+// stored under the requested hash.
+var ErrNodeNotFound = errors.New("blake: node not found")
+
+// NodeStore persists tree nodes keyed by their hash.
+type NodeStore interface {
+	// Get returns the value stored under hash, or ErrNodeNotFound.
+	Get(hash [Size256]byte) ([]byte, error)
+
+	// Put stores value under hash.
+	Put(hash [Size256]byte, value []byte) error
+}
+
+// MemoryNodeStore is a NodeStore backed by a map. It is safe for
+// concurrent use.
+type MemoryNodeStore struct {
+	mu    sync.RWMutex
+	nodes map[[Size256]byte][]byte
+}
+
+// NewMemoryNodeStore returns an empty MemoryNodeStore.
+func NewMemoryNodeStore() *MemoryNodeStore {
+	return &MemoryNodeStore{nodes: make(map[[Size256]byte][]byte)}
+}
+
+func (s *MemoryNodeStore) Get(hash [Size256]byte) ([]byte, error) {
+	s.mu.RLock()
+	v, ok := s.nodes[hash]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrNodeNotFound
+	}
+	return v, nil
+}
+
+func (s *MemoryNodeStore) Put(hash [Size256]byte, value []byte) error {
+	s.mu.Lock()
+	s.nodes[hash] = append([]byte(nil), value...)
+	s.mu.Unlock()
+	return nil
+}
+
+// Len returns the number of stored nodes.
+func (s *MemoryNodeStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.nodes)
+}
+
+// sparseDefaults holds the hashes of empty subtrees, indexed by
+// depth. The empty leaf at depth SparseDepth hashes to all zeros.
+var sparseDefaults = func() (d [SparseDepth + 1][Size256]byte) {
+	for i := SparseDepth - 1; i >= 0; i-- {
+		d[i] = MerkleNodeHash(d[i+1], d[i+1])
+	}
+	return
+}()
+
+// sparseLeafHash returns H(0x00 || path || value).
+func sparseLeafHash(path, value *[Size256]byte) [Size256]byte {
+	d := merkleLeafState
+	d.Write(path[:])
+	d.Write(value[:])
+	return d.checkSum()
+}
+
+// sparseBit returns the bit of path at the given depth, most
+// significant bit first.
+func sparseBit(path *[Size256]byte, depth int) byte {
+	return path[depth>>3] >> (7 - uint(depth&7)) & 1
+}
+
+// SparseMerkleTree is a sparse Merkle tree of depth 256 over
+// BLAKE-256. A key is stored at the leaf indexed by Sum256(key) and
+// the tree commits to the BLAKE-256 checksum of its value.
+//
+// Interior nodes are stored as left || right and non-empty leaves as
+// path || Sum256(value) under their hash. Nodes are never removed, so
+// earlier roots remain valid for lookups and proofs.
+type SparseMerkleTree struct {
+	store NodeStore
+	root  [Size256]byte
+}
+
+// NewSparseMerkleTree returns an empty SparseMerkleTree backed by
+// store. If store is nil, a MemoryNodeStore is used.
+func NewSparseMerkleTree(store NodeStore) *SparseMerkleTree {
+	return LoadSparseMerkleTree(store, sparseDefaults[0])
+}
+
+// LoadSparseMerkleTree returns a SparseMerkleTree with the given root
+// whose nodes are held in store.
+func LoadSparseMerkleTree(store NodeStore, root [Size256]byte) *SparseMerkleTree {
+	if store == nil {
+		store = NewMemoryNodeStore()
+	}
+	return &SparseMerkleTree{store: store, root: root}
+}
+
+// Root returns the root hash of the tree.
+func (t *SparseMerkleTree) Root() [Size256]byte { return t.root }
+
+// children returns the children of the node with hash h at depth.
+func (t *SparseMerkleTree) children(h [Size256]byte, depth int) (l, r [Size256]byte, err error) {
+	if h == sparseDefaults[depth] {
+		return sparseDefaults[depth+1], sparseDefaults[depth+1], nil
+	}
+	v, err := t.store.Get(h)
+	if err != nil {
+		return
+	}
+	if len(v) != 2*Size256 {
+		return l, r, errors.New("blake: corrupt sparse Merkle tree node")
+	}
+	copy(l[:], v[:Size256])
+	copy(r[:], v[Size256:])
+	return
+}
+
+// walk descends from the root to the leaf at path and returns the
+// leaf hash and the siblings from the top down.
+func (t *SparseMerkleTree) walk(path *[Size256]byte, siblings *[SparseDepth][Size256]byte) ([Size256]byte, error) {
+	h := t.root
+	for depth := 0; depth < SparseDepth; depth++ {
+		l, r, err := t.children(h, depth)
+		if err != nil {
+			return h, err
+		}
+		if sparseBit(path, depth) == 0 {
+			h = l
+			if siblings != nil {
+				siblings[depth] = r
+			}
+		} else {
+			h = r
+			if siblings != nil {
+				siblings[depth] = l
+			}
+		}
+	}
+	return h, nil
+}
+
+// Get returns the BLAKE-256 checksum of the value stored under key.
+// ok is false if the key is not present.
+func (t *SparseMerkleTree) Get(key []byte) (value [Size256]byte, ok bool, err error) {
+	path := Sum256(key)
+	leaf, err := t.walk(&path, nil)
+	if err != nil || leaf == sparseDefaults[SparseDepth] {
+		return
+	}
+	v, err := t.store.Get(leaf)
+	if err != nil {
+		return
+	}
+	if len(v) != 2*Size256 {
+		return value, false, errors.New("blake: corrupt sparse Merkle tree leaf")
+	}
+	copy(value[:], v[Size256:])
+	return value, true, nil
+}
+
+// Update sets the value stored under key. A nil value removes the
+// key from the tree.
+func (t *SparseMerkleTree) Update(key, value []byte) error {
+	return t.UpdateBatch([][]byte{key}, [][]byte{value})
+}
+
+// Delete removes key from the tree.
+func (t *SparseMerkleTree) Delete(key []byte) error {
+	return t.Update(key, nil)
+}
+
+type sparseUpdate struct {
+	path  [Size256]byte
+	value [Size256]byte
+	del   bool
+}
+
+// UpdateBatch applies keys[i] = values[i] for every i, hashing each
+// affected interior node only once. A nil value removes the key. If a
+// key occurs more than once, the last value wins.
+func (t *SparseMerkleTree) UpdateBatch(keys, values [][]byte) error {
+	if len(keys) != len(values) {
+		return errors.New("blake: mismatched number of keys and values")
+	}
+	ups := make([]sparseUpdate, len(keys))
+	for i, k := range keys {
+		ups[i].path = Sum256(k)
+		if values[i] == nil {
+			ups[i].del = true
+		} else {
+			ups[i].value = Sum256(values[i])
+		}
+	}
+	sort.SliceStable(ups, func(i, j int) bool {
+		return string(ups[i].path[:]) < string(ups[j].path[:])
+	})
+	root, err := t.update(t.root, 0, ups)
+	if err != nil {
+		return err
+	}
+	t.root = root
+	return nil
+}
+
+// update applies ups, sorted by path, to the subtree with hash h at
+// depth and returns the new subtree hash.
+func (t *SparseMerkleTree) update(h [Size256]byte, depth int, ups []sparseUpdate) ([Size256]byte, error) {
+	if len(ups) == 0 {
+		return h, nil
+	}
+	if depth == SparseDepth {
+		u := &ups[len(ups)-1]
+		if u.del {
+			return sparseDefaults[SparseDepth], nil
+		}
+		leaf := sparseLeafHash(&u.path, &u.value)
+		var v [2 * Size256]byte
+		copy(v[:], u.path[:])
+		copy(v[Size256:], u.value[:])
+		return leaf, t.store.Put(leaf, v[:])
+	}
+
+	l, r, err := t.children(h, depth)
+	if err != nil {
+		return h, err
+	}
+	i := sort.Search(len(ups), func(i int) bool { return sparseBit(&ups[i].path, depth) == 1 })
+	if l, err = t.update(l, depth+1, ups[:i]); err != nil {
+		return h, err
+	}
+	if r, err = t.update(r, depth+1, ups[i:]); err != nil {
+		return h, err
+	}
+	if l == sparseDefaults[depth+1] && r == sparseDefaults[depth+1] {
+		return sparseDefaults[depth], nil
+	}
+	h = MerkleNodeHash(l, r)
+	var v [2 * Size256]byte
+	copy(v[:], l[:])
+	copy(v[Size256:], r[:])
+	return h, t.store.Put(h, v[:])
+}
+
+// SparseMerkleProof proves the presence or absence of a key in a
+// SparseMerkleTree.
+type SparseMerkleProof struct {
+	// Siblings holds the sibling of every node on the path from the
+	// root to the leaf, starting below the root.
+	Siblings [SparseDepth][Size256]byte
+}
+
+// Prove returns a proof for key. It proves membership if the key is
+// present and non-membership otherwise.
+func (t *SparseMerkleTree) Prove(key []byte) (*SparseMerkleProof, error) {
+	path := Sum256(key)
+	p := new(SparseMerkleProof)
+	if _, err := t.walk(&path, &p.Siblings); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// VerifySparseMerkleProof checks proof against root for key. If value
+// is nil, it checks that key is absent; otherwise it checks that key
+// is present with the given value.
+func VerifySparseMerkleProof(root [Size256]byte, key, value []byte, proof *SparseMerkleProof) error {
+	path := Sum256(key)
+	h := sparseDefaults[SparseDepth]
+	if value != nil {
+		v := Sum256(value)
+		h = sparseLeafHash(&path, &v)
+	}
+	for depth := SparseDepth - 1; depth >= 0; depth-- {
+		if sparseBit(&path, depth) == 0 {
+			h = MerkleNodeHash(h, proof.Siblings[depth])
+		} else {
+			h = MerkleNodeHash(proof.Siblings[depth], h)
+		}
+	}
+	if h != root {
+		return ErrInvalidProof
+	}
+	return nil
+}
+
+// MarshalBinary encodes the proof compactly as a 32-byte bitmap
+// marking the siblings which are not empty subtrees, followed by
+// those siblings in order.
+func (p *SparseMerkleProof) MarshalBinary() ([]byte, error) {
+	b := make([]byte, SparseDepth/8, SparseDepth/8+Size256*8)
+	for i := range p.Siblings {
+		if p.Siblings[i] != sparseDefaults[i+1] {
+			b[i>>3] |= 0x80 >> uint(i&7)
+			b = append(b, p.Siblings[i][:]...)
+		}
+	}
+	return b, nil
+}
+
+// UnmarshalBinary decodes a proof produced by MarshalBinary.
+func (p *SparseMerkleProof) UnmarshalBinary(b []byte) error {
+	if len(b) < SparseDepth/8 {
+		return errors.New("blake: sparse Merkle proof too short")
+	}
+	bitmap, rest := b[:SparseDepth/8], b[SparseDepth/8:]
+	n := 0
+	for _, c := range bitmap {
+		n += bits.OnesCount8(c)
+	}
+	if len(rest) != n*Size256 {
+		return errors.New("blake: invalid sparse Merkle proof length")
+	}
+	for i := range p.Siblings {
+		if bitmap[i>>3]&(0x80>>uint(i&7)) != 0 {
+			copy(p.Siblings[i][:], rest)
+			rest = rest[Size256:]
+		} else {
+			p.Siblings[i] = sparseDefaults[i+1]
+		}
+	}
+	return nil
+}
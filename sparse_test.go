@@ -0,0 +1,121 @@
+package blake
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSparseEmpty(t *testing.T) {
+	tree := NewSparseMerkleTree(nil)
+	if tree.Root() != sparseDefaults[0] {
+		t.Error("empty tree root differs from the default root")
+	}
+	if _, ok, err := tree.Get([]byte("key")); ok || err != nil {
+		t.Errorf("Get on empty tree: ok=%v err=%v", ok, err)
+	}
+	p, err := tree.Prove([]byte("key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifySparseMerkleProof(tree.Root(), []byte("key"), nil, p); err != nil {
+		t.Errorf("non-membership proof in empty tree: %v", err)
+	}
+}
+
+func TestSparseUpdate(t *testing.T) {
+	store := NewMemoryNodeStore()
+	tree := NewSparseMerkleTree(store)
+	for i := 0; i < 20; i++ {
+		k := []byte(fmt.Sprintf("key %d", i))
+		if err := tree.Update(k, []byte(fmt.Sprintf("value %d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		k, v := []byte(fmt.Sprintf("key %d", i)), []byte(fmt.Sprintf("value %d", i))
+		got, ok, err := tree.Get(k)
+		if err != nil || !ok || got != Sum256(v) {
+			t.Errorf("Get(%q) = %x, %v, %v", k, got, ok, err)
+		}
+		p, err := tree.Prove(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifySparseMerkleProof(tree.Root(), k, v, p); err != nil {
+			t.Errorf("membership proof for %q: %v", k, err)
+		}
+		if VerifySparseMerkleProof(tree.Root(), k, nil, p) == nil {
+			t.Errorf("non-membership proof for present key %q verified", k)
+		}
+		if VerifySparseMerkleProof(tree.Root(), k, []byte("other"), p) == nil {
+			t.Errorf("membership proof for %q verified with wrong value", k)
+		}
+	}
+
+	// Historical roots remain readable.
+	old := tree.Root()
+	if err := tree.Delete([]byte("key 3")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := tree.Get([]byte("key 3")); ok {
+		t.Error("deleted key is still present")
+	}
+	if _, ok, _ := LoadSparseMerkleTree(store, old).Get([]byte("key 3")); !ok {
+		t.Error("deleted key is missing from the previous root")
+	}
+
+	// Deleting every key returns to the empty root.
+	for i := 0; i < 20; i++ {
+		tree.Delete([]byte(fmt.Sprintf("key %d", i)))
+	}
+	if tree.Root() != sparseDefaults[0] {
+		t.Error("root after deleting every key differs from the empty root")
+	}
+}
+
+func TestSparseBatch(t *testing.T) {
+	var keys, values [][]byte
+	single := NewSparseMerkleTree(nil)
+	for i := 0; i < 50; i++ {
+		k, v := []byte(fmt.Sprintf("key %d", i)), []byte(fmt.Sprintf("value %d", i))
+		keys, values = append(keys, k), append(values, v)
+		single.Update(k, v)
+	}
+	keys, values = append(keys, keys[0]), append(values, []byte("last"))
+	single.Update(keys[0], []byte("last"))
+
+	batch := NewSparseMerkleTree(nil)
+	if err := batch.UpdateBatch(keys, values); err != nil {
+		t.Fatal(err)
+	}
+	if batch.Root() != single.Root() {
+		t.Error("batched update root differs from sequential updates")
+	}
+	if err := batch.UpdateBatch(keys, values[1:]); err == nil {
+		t.Error("expected error for mismatched keys and values")
+	}
+}
+
+func TestSparseProofEncoding(t *testing.T) {
+	tree := NewSparseMerkleTree(nil)
+	tree.Update([]byte("a"), []byte("1"))
+	tree.Update([]byte("b"), []byte("2"))
+	p, _ := tree.Prove([]byte("a"))
+	b, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) >= 2*Size256+SparseDepth/8 {
+		t.Errorf("compact proof is %d bytes", len(b))
+	}
+	var q SparseMerkleProof
+	if err := q.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if q != *p {
+		t.Error("decoded proof differs from the original")
+	}
+	if q.UnmarshalBinary(b[:len(b)-1]) == nil {
+		t.Error("expected error for truncated proof")
+	}
+}
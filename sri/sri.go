@@ -0,0 +1,192 @@
+// Package sri formats, parses and verifies Subresource Integrity style
+// metadata for BLAKE digests:
+//
+//	blake256-<base64 digest>
+//
+// An integrity string holds one or more such values separated by
+// whitespace. As in the SRI specification, values with unknown
+// algorithms or malformed digests are ignored, and only the values with
+// the strongest algorithm present are used for verification. Options
+// after a '?' are kept but not interpreted.
+package sri
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/ouzklcn/blake"
+)
+
+// Algorithm is a BLAKE variant usable in integrity metadata. Larger
+// values are stronger.
+type Algorithm int
+
+// Supported algorithms, weakest first.
+const (
+	BLAKE224 Algorithm = iota + 1
+	BLAKE256
+	BLAKE384
+	BLAKE512
+)
+
+var algorithms = [...]struct {
+	name string
+	size int
+	new  func() hash.Hash
+}{
+	BLAKE224: {"blake224", blake.Size224, blake.New224},
+	BLAKE256: {"blake256", blake.Size256, blake.New256},
+	BLAKE384: {"blake384", blake.Size384, blake.New384},
+	BLAKE512: {"blake512", blake.Size512, blake.New512},
+}
+
+var (
+	// ErrNoMetadata is returned when an integrity string holds no
+	// value with a supported algorithm.
+	ErrNoMetadata = errors.New("sri: no supported integrity metadata")
+	// ErrMismatch is returned when content matches none of the
+	// strongest values.
+	ErrMismatch = errors.New("sri: integrity mismatch")
+)
+
+func (a Algorithm) valid() bool {
+	return a > 0 && int(a) < len(algorithms)
+}
+
+func (a Algorithm) String() string {
+	if !a.valid() {
+		return "unknown"
+	}
+	return algorithms[a].name
+}
+
+// New returns a new hash.Hash computing the checksum for a.
+func (a Algorithm) New() hash.Hash {
+	if !a.valid() {
+		panic("sri: unknown algorithm")
+	}
+	return algorithms[a].new()
+}
+
+// Metadata is a single integrity value.
+type Metadata struct {
+	Algorithm Algorithm
+	Digest    []byte
+	Options   string
+}
+
+// String returns m in the form "<alg>-<base64>[?options]".
+func (m Metadata) String() string {
+	s := m.Algorithm.String() + "-" + base64.StdEncoding.EncodeToString(m.Digest)
+	if m.Options != "" {
+		s += "?" + m.Options
+	}
+	return s
+}
+
+// Sum returns the integrity value of data for the given algorithm.
+func Sum(a Algorithm, data []byte) Metadata {
+	h := a.New()
+	h.Write(data)
+	return Metadata{Algorithm: a, Digest: h.Sum(nil)}
+}
+
+// Digest reads r to EOF and returns its integrity value for the given
+// algorithm.
+func Digest(a Algorithm, r io.Reader) (Metadata, error) {
+	h := a.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return Metadata{}, err
+	}
+	return Metadata{Algorithm: a, Digest: h.Sum(nil)}, nil
+}
+
+// Format joins integrity values with spaces.
+func Format(ms ...Metadata) string {
+	s := make([]string, len(ms))
+	for i, m := range ms {
+		s[i] = m.String()
+	}
+	return strings.Join(s, " ")
+}
+
+// Parse returns the well-formed values with a supported algorithm in
+// an integrity string, in order. Other values are skipped.
+func Parse(integrity string) []Metadata {
+	var ms []Metadata
+	for _, field := range strings.Fields(integrity) {
+		if m, ok := parseOne(field); ok {
+			ms = append(ms, m)
+		}
+	}
+	return ms
+}
+
+func parseOne(s string) (Metadata, bool) {
+	name, rest, ok := strings.Cut(s, "-")
+	if !ok {
+		return Metadata{}, false
+	}
+	var m Metadata
+	for a := BLAKE224; a.valid(); a++ {
+		if strings.EqualFold(name, algorithms[a].name) {
+			m.Algorithm = a
+		}
+	}
+	if m.Algorithm == 0 {
+		return Metadata{}, false
+	}
+	rest, m.Options, _ = strings.Cut(rest, "?")
+	var err error
+	if m.Digest, err = base64.StdEncoding.DecodeString(rest); err != nil {
+		if m.Digest, err = base64.URLEncoding.DecodeString(rest); err != nil {
+			return Metadata{}, false
+		}
+	}
+	if len(m.Digest) != algorithms[m.Algorithm].size {
+		return Metadata{}, false
+	}
+	return m, true
+}
+
+// Strongest returns the values in ms with the strongest algorithm.
+func Strongest(ms []Metadata) []Metadata {
+	var best Algorithm
+	for _, m := range ms {
+		if m.Algorithm > best {
+			best = m.Algorithm
+		}
+	}
+	var out []Metadata
+	for _, m := range ms {
+		if m.Algorithm == best {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// Verify reads r to EOF and checks it against the strongest values in
+// an integrity string. It returns nil if any of them matches,
+// ErrMismatch if none does and ErrNoMetadata if the string has no
+// supported values.
+func Verify(r io.Reader, integrity string) error {
+	ms := Strongest(Parse(integrity))
+	if len(ms) == 0 {
+		return ErrNoMetadata
+	}
+	got, err := Digest(ms[0].Algorithm, r)
+	if err != nil {
+		return err
+	}
+	for _, m := range ms {
+		if subtle.ConstantTimeCompare(got.Digest, m.Digest) == 1 {
+			return nil
+		}
+	}
+	return ErrMismatch
+}
@@ -0,0 +1,66 @@
+package sri
+
+import (
+	"strings"
+	"testing"
+)
+
+const blake256BLAKE = "blake256-B2Y+AM+W+8E2z3se4JnJU0a6OSCJPRjMiFHyLuLjaqY="
+
+func TestFormat(t *testing.T) {
+	if got := Sum(BLAKE256, []byte("BLAKE")).String(); got != blake256BLAKE {
+		t.Errorf("expected %s, got %s", blake256BLAKE, got)
+	}
+	m, err := Digest(BLAKE512, strings.NewReader("BLAKE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Options = "ct=text/plain"
+	s := Format(Sum(BLAKE224, []byte("BLAKE")), m)
+	ms := Parse(s)
+	if len(ms) != 2 || ms[0].Algorithm != BLAKE224 || ms[1].String() != m.String() {
+		t.Errorf("Parse(%s) = %v", s, ms)
+	}
+}
+
+func TestParse(t *testing.T) {
+	d384 := Sum(BLAKE384, []byte("x")).String()
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"", 0},
+		{"sha256-abc " + blake256BLAKE, 1},
+		{"blake256-!!!! blake256-AAAA", 0},
+		{"BLAKE256-" + blake256BLAKE[len("blake256-"):], 1},
+		{"  " + blake256BLAKE + "\t" + d384 + "\n", 2},
+		{"blake512-" + blake256BLAKE[len("blake256-"):], 0},
+	}
+	for i, tt := range tests {
+		if got := Parse(tt.in); len(got) != tt.want {
+			t.Errorf("%d: expected %d values, got %v", i, tt.want, got)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	good := Sum(BLAKE512, []byte("Golang")).String()
+	bad := Sum(BLAKE512, []byte("golang")).String()
+	weak := Sum(BLAKE224, []byte("other")).String()
+	tests := []struct {
+		integrity string
+		err       error
+	}{
+		{good, nil},
+		{bad + " " + good, nil},
+		{weak + " " + good, nil},
+		{bad, ErrMismatch},
+		{Sum(BLAKE224, []byte("Golang")).String() + " " + bad, ErrMismatch},
+		{"sha384-abc", ErrNoMetadata},
+	}
+	for i, tt := range tests {
+		if err := Verify(strings.NewReader("Golang"), tt.integrity); err != tt.err {
+			t.Errorf("%d: expected %v, got %v", i, tt.err, err)
+		}
+	}
+}
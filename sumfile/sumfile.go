@@ -0,0 +1,214 @@
+// Package sumfile reads, writes and verifies checksum lists of BLAKE
+// digests in the formats of the GNU coreutils *sum programs:
+//
+//	GNU:  <hex>  <path>        text mode
+//	      <hex> *<path>        binary mode
+//	BSD:  BLAKE-256 (<path>) = <hex>
+//
+// As with coreutils, a path containing a backslash, newline or carriage
+// return is written with these escaped as \\, \n and \r, and the line
+// is prefixed with a backslash. The GNU format does not name the
+// algorithm, so it is inferred from the digest length when parsing.
+package sumfile
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/ouzklcn/blake"
+)
+
+// Algorithm is a BLAKE variant.
+type Algorithm int
+
+// Supported algorithms.
+const (
+	BLAKE224 Algorithm = iota + 1
+	BLAKE256
+	BLAKE384
+	BLAKE512
+)
+
+var algorithms = [...]struct {
+	name string
+	size int
+	new  func() hash.Hash
+}{
+	BLAKE224: {"BLAKE-224", blake.Size224, blake.New224},
+	BLAKE256: {"BLAKE-256", blake.Size256, blake.New256},
+	BLAKE384: {"BLAKE-384", blake.Size384, blake.New384},
+	BLAKE512: {"BLAKE-512", blake.Size512, blake.New512},
+}
+
+func (a Algorithm) valid() bool {
+	return a > 0 && int(a) < len(algorithms)
+}
+
+func (a Algorithm) String() string {
+	if !a.valid() {
+		return "unknown"
+	}
+	return algorithms[a].name
+}
+
+// New returns a new hash.Hash computing the checksum for a.
+func (a Algorithm) New() hash.Hash {
+	if !a.valid() {
+		panic("sumfile: unknown algorithm")
+	}
+	return algorithms[a].new()
+}
+
+// Format is a checksum line format.
+type Format int
+
+// Supported formats.
+const (
+	GNU Format = iota // "<hex>  <path>"
+	BSD               // "BLAKE-256 (<path>) = <hex>"
+)
+
+// Entry is a line of a checksum list.
+type Entry struct {
+	Algorithm Algorithm
+	Digest    []byte
+	Path      string
+	Binary    bool // binary mode marker of the GNU format
+}
+
+// ParseError is returned for improperly formatted lines.
+type ParseError struct {
+	Line int // line number, starting at 1
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("sumfile: line %d: improperly formatted checksum line", e.Line)
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`)
+
+// Line returns e formatted as a line, including the trailing newline.
+func (e Entry) Line(f Format) string {
+	var b strings.Builder
+	name := e.Path
+	if strings.ContainsAny(name, "\\\n\r") {
+		b.WriteByte('\\')
+		name = escaper.Replace(name)
+	}
+	sum := hex.EncodeToString(e.Digest)
+	if f == BSD {
+		fmt.Fprintf(&b, "%s (%s) = %s\n", e.Algorithm, name, sum)
+		return b.String()
+	}
+	marker := " "
+	if e.Binary {
+		marker = "*"
+	}
+	fmt.Fprintf(&b, "%s %s%s\n", sum, marker, name)
+	return b.String()
+}
+
+// Write writes entries to w in the given format.
+func Write(w io.Writer, entries []Entry, f Format) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range entries {
+		bw.WriteString(e.Line(f))
+	}
+	return bw.Flush()
+}
+
+// Parse reads a checksum list in either format, which may be mixed.
+// Empty lines and lines starting with '#' are skipped.
+func Parse(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSuffix(s.Text(), "\r")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		e, ok := parseLine(line)
+		if !ok {
+			return nil, &ParseError{Line: n}
+		}
+		entries = append(entries, e)
+	}
+	return entries, s.Err()
+}
+
+func parseLine(line string) (Entry, bool) {
+	escaped := line[0] == '\\'
+	if escaped {
+		line = line[1:]
+	}
+	var e Entry
+	var name, sum string
+	if alg, rest, ok := strings.Cut(line, " ("); ok && strings.HasPrefix(alg, "BLAKE-") {
+		i := strings.LastIndex(rest, ") = ")
+		if i < 0 {
+			return Entry{}, false
+		}
+		name, sum = rest[:i], rest[i+4:]
+		for a := BLAKE224; a.valid(); a++ {
+			if alg == algorithms[a].name {
+				e.Algorithm = a
+			}
+		}
+	} else {
+		i := strings.IndexByte(line, ' ')
+		if i < 0 || i+2 > len(line) || (line[i+1] != ' ' && line[i+1] != '*') {
+			return Entry{}, false
+		}
+		sum, name = line[:i], line[i+2:]
+		e.Binary = line[i+1] == '*'
+		for a := BLAKE224; a.valid(); a++ {
+			if len(sum) == 2*algorithms[a].size {
+				e.Algorithm = a
+			}
+		}
+	}
+	if e.Algorithm == 0 || len(sum) != 2*algorithms[e.Algorithm].size || name == "" {
+		return Entry{}, false
+	}
+	var err error
+	if e.Digest, err = hex.DecodeString(sum); err != nil {
+		return Entry{}, false
+	}
+	if escaped {
+		var ok bool
+		if name, ok = unescape(name); !ok {
+			return Entry{}, false
+		}
+	}
+	e.Path = name
+	return e, true
+}
+
+// unescape reverses the escaping of a path.
+func unescape(s string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i++; i == len(s) {
+			return "", false
+		}
+		switch s[i] {
+		case '\\':
+			b.WriteByte('\\')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
@@ -0,0 +1,84 @@
+package sumfile
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ouzklcn/blake"
+)
+
+const blake256BLAKE = "07663e00cf96fbc136cf7b1ee099c95346ba3920893d18cc8851f22ee2e36aa6"
+
+func TestLine(t *testing.T) {
+	sum := blake.Sum256([]byte("BLAKE"))
+	e := Entry{Algorithm: BLAKE256, Digest: sum[:], Path: "dir/file.txt"}
+	tests := []struct {
+		e    Entry
+		f    Format
+		want string
+	}{
+		{e, GNU, blake256BLAKE + "  dir/file.txt\n"},
+		{e, BSD, "BLAKE-256 (dir/file.txt) = " + blake256BLAKE + "\n"},
+		{Entry{BLAKE256, sum[:], "a.bin", true}, GNU, blake256BLAKE + " *a.bin\n"},
+		{Entry{BLAKE256, sum[:], "a\\b\nc", false}, GNU, "\\" + blake256BLAKE + "  a\\\\b\\nc\n"},
+		{Entry{BLAKE256, sum[:], "a\nb", false}, BSD, "\\BLAKE-256 (a\\nb) = " + blake256BLAKE + "\n"},
+	}
+	for i, tt := range tests {
+		if got := tt.e.Line(tt.f); got != tt.want {
+			t.Errorf("%d: expected %q, got %q", i, tt.want, got)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	var entries []Entry
+	for i, a := range []Algorithm{BLAKE224, BLAKE256, BLAKE384, BLAKE512} {
+		h := a.New()
+		h.Write([]byte("Golang"))
+		entries = append(entries, Entry{a, h.Sum(nil), []string{"a", "b c", "d\\e", "f\r\ng (1).txt"}[i], i%2 == 1})
+	}
+	for _, f := range []Format{GNU, BSD} {
+		var buf bytes.Buffer
+		if err := Write(&buf, entries, f); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Parse(&buf)
+		if err != nil {
+			t.Fatalf("format %d: %v", f, err)
+		}
+		if len(got) != len(entries) {
+			t.Fatalf("format %d: expected %d entries, got %d", f, len(entries), len(got))
+		}
+		for i := range got {
+			want := entries[i]
+			if f == BSD {
+				want.Binary = false
+			}
+			if got[i].Algorithm != want.Algorithm || got[i].Path != want.Path ||
+				got[i].Binary != want.Binary || !bytes.Equal(got[i].Digest, want.Digest) {
+				t.Errorf("format %d, entry %d: expected %+v, got %+v", f, i, want, got[i])
+			}
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	good := blake256BLAKE + "  file\n"
+	tests := []string{
+		"xyz  file",
+		blake256BLAKE + " file",
+		blake256BLAKE[:63] + "  file",
+		blake256BLAKE + "  ",
+		"BLAKE-256 (file) " + blake256BLAKE,
+		"BLAKE-224 (file) = " + blake256BLAKE,
+		"SHA-256 (file) = " + blake256BLAKE,
+		"\\" + blake256BLAKE + "  bad\\escape",
+	}
+	for i, line := range tests {
+		_, err := Parse(strings.NewReader("# comment\n\n" + good + line + "\n"))
+		if pe, ok := err.(*ParseError); !ok || pe.Line != 4 {
+			t.Errorf("%d: expected ParseError on line 4, got %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package sumfile
+
+import (
+	"crypto/subtle"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Status is the outcome of verifying an entry.
+type Status int
+
+// Verification outcomes.
+const (
+	OK      Status = iota
+	Failed         // contents differ, or the file could not be read
+	Missing        // the file does not exist
+)
+
+func (s Status) String() string {
+	switch s {
+	case OK:
+		return "OK"
+	case Failed:
+		return "FAILED"
+	case Missing:
+		return "missing"
+	}
+	return "unknown"
+}
+
+// Result is the outcome of verifying an entry.
+type Result struct {
+	Entry  Entry
+	Status Status
+	Err    error // read error, if any
+}
+
+// String formats r like the coreutils --check output, "<path>: OK".
+func (r Result) String() string {
+	return r.Entry.Path + ": " + r.Status.String()
+}
+
+// fsPath converts a path from a checksum list to an fs.FS path.
+func fsPath(name string) (string, error) {
+	p := path.Clean(strings.TrimPrefix(name, "./"))
+	if !fs.ValidPath(p) {
+		return "", &fs.PathError{Op: "verify", Path: name, Err: fs.ErrInvalid}
+	}
+	return p, nil
+}
+
+var errAlgorithm = errors.New("sumfile: unknown algorithm")
+
+func sumFile(fsys fs.FS, name string, a Algorithm) ([]byte, error) {
+	if !a.valid() {
+		return nil, &fs.PathError{Op: "verify", Path: name, Err: errAlgorithm}
+	}
+	p, err := fsPath(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := fsys.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := a.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// Sum computes the entries of the named files in fsys.
+func Sum(fsys fs.FS, names []string, a Algorithm) ([]Entry, error) {
+	entries := make([]Entry, len(names))
+	for i, name := range names {
+		d, err := sumFile(fsys, name, a)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = Entry{Algorithm: a, Digest: d, Path: name}
+	}
+	return entries, nil
+}
+
+// Verify checks every entry against the files in fsys and returns a
+// result per entry, in order. Paths may start with "./" but must
+// otherwise be valid fs.FS paths.
+func Verify(fsys fs.FS, entries []Entry) []Result {
+	results := make([]Result, len(entries))
+	for i, e := range entries {
+		results[i].Entry = e
+		d, err := sumFile(fsys, e.Path, e.Algorithm)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			results[i].Status = Missing
+			results[i].Err = err
+		case err != nil:
+			results[i].Status = Failed
+			results[i].Err = err
+		case subtle.ConstantTimeCompare(d, e.Digest) != 1:
+			results[i].Status = Failed
+		}
+	}
+	return results
+}
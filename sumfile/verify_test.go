@@ -0,0 +1,46 @@
+package sumfile
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestVerify(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":     {Data: []byte("BLAKE")},
+		"dir/b.txt": {Data: []byte("Golang")},
+	}
+	entries, err := Sum(fsys, []string{"a.txt", "./dir/b.txt"}, BLAKE512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries = append(entries, Entry{BLAKE512, entries[0].Digest, "gone.txt", false})
+	entries = append(entries, Entry{BLAKE512, entries[0].Digest, "../etc/passwd", false})
+	entries = append(entries, Entry{0, entries[0].Digest, "a.txt", false})
+	fsys["dir/b.txt"].Data = []byte("golang")
+
+	results := Verify(fsys, entries)
+	want := []struct {
+		status Status
+		err    bool
+		line   string
+	}{
+		{OK, false, "a.txt: OK"},
+		{Failed, false, "./dir/b.txt: FAILED"},
+		{Missing, true, "gone.txt: missing"},
+		{Failed, true, "../etc/passwd: FAILED"},
+		{Failed, true, "a.txt: FAILED"},
+	}
+	for i, r := range results {
+		if r.Status != want[i].status || (r.Err != nil) != want[i].err || r.String() != want[i].line {
+			t.Errorf("%d: expected %s, got %s (%v)", i, want[i].line, r, r.Err)
+		}
+	}
+
+	if _, err := Sum(fsys, []string{"gone.txt"}, BLAKE256); err == nil {
+		t.Error("expected error summing a missing file")
+	}
+	if _, err := Sum(fsys, []string{"a.txt"}, BLAKE512+1); err == nil {
+		t.Error("expected error summing with an unknown algorithm")
+	}
+}
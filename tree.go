@@ -0,0 +1,243 @@
+package blake
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultTreeLeafSize is the leaf size used by the tree hash mode
+// when TreeConfig.LeafSize is zero.
+const DefaultTreeLeafSize = 1 << 20
+
+// TreeConfig configures the tree hash mode.
+//
+// In tree mode the input is split into leaves of LeafSize bytes, the
+// last one possibly shorter; empty input is a single empty leaf. Leaves
+// and parents are hashed with distinct salts and arranged in an
+// RFC 6962 shaped tree, whose left subtree holds the largest power of
+// two number of leaves. The root is the tree hash finalized with a
+// third salt over the input length and leaf size, so the result only
+// depends on the input and LeafSize, never on Workers.
+type TreeConfig struct {
+	// LeafSize is the size of a leaf in bytes. Zero means
+	// DefaultTreeLeafSize.
+	LeafSize int
+
+	// Workers is the number of goroutines reading and hashing
+	// leaves. Zero means runtime.GOMAXPROCS(0).
+	Workers int
+}
+
+var errTreeConfig = errors.New("blake: invalid tree leaf size or input size")
+
+// leafSize returns the configured leaf size after checking it and the
+// input size.
+func (c *TreeConfig) leafSize(size int64) (int, error) {
+	if size < 0 || c != nil && c.LeafSize < 0 {
+		return 0, errTreeConfig
+	}
+	if c == nil || c.LeafSize == 0 {
+		return DefaultTreeLeafSize, nil
+	}
+	return c.LeafSize, nil
+}
+
+func (c *TreeConfig) workers() int {
+	if c == nil || c.Workers <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return c.Workers
+}
+
+// Salts separating leaves, parents and roots of the tree mode.
+var (
+	treeLeafSalt = []byte("blake tree leaf\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")
+	treeNodeSalt = []byte("blake tree node\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")
+	treeRootSalt = []byte("blake tree root\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")
+
+	treeLeaf256 = saltedState256(treeLeafSalt[:16])
+	treeNode256 = saltedState256(treeNodeSalt[:16])
+	treeRoot256 = saltedState256(treeRootSalt[:16])
+	treeLeaf512 = saltedState512(treeLeafSalt)
+	treeNode512 = saltedState512(treeNodeSalt)
+	treeRoot512 = saltedState512(treeRootSalt)
+)
+
+func saltedState256(salt []byte) digest256 {
+	var d digest256
+	d.setSalt(salt)
+	d.Reset()
+	return d
+}
+
+func saltedState512(salt []byte) digest512 {
+	var d digest512
+	d.setSalt(salt)
+	d.Reset()
+	return d
+}
+
+// treeLeaves returns the number of leaves of an input of the given
+// size.
+func treeLeaves(size int64, leafSize int) int64 {
+	if size == 0 {
+		return 1
+	}
+	return (size + int64(leafSize) - 1) / int64(leafSize)
+}
+
+// treeSplit returns the number of leaves in the left subtree of a
+// tree with n leaves. n must be greater than one.
+func treeSplit(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// treeParams encodes the input length and leaf size for the root.
+func treeParams(size int64, leafSize int) []byte {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(size))
+	binary.BigEndian.PutUint64(b[8:], uint64(leafSize))
+	return b[:]
+}
+
+func treeLeafHash256(p []byte) [Size256]byte {
+	d := treeLeaf256
+	d.Write(p)
+	return d.checkSum()
+}
+
+func treeReduce256(leaves [][Size256]byte) [Size256]byte {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	k := treeSplit(len(leaves))
+	l, r := treeReduce256(leaves[:k]), treeReduce256(leaves[k:])
+	d := treeNode256
+	d.Write(l[:])
+	d.Write(r[:])
+	return d.checkSum()
+}
+
+func treeFinal256(size int64, leafSize int, tree [Size256]byte) [Size256]byte {
+	d := treeRoot256
+	d.Write(treeParams(size, leafSize))
+	d.Write(tree[:])
+	return d.checkSum()
+}
+
+func treeLeafHash512(p []byte) [Size512]byte {
+	d := treeLeaf512
+	d.Write(p)
+	return d.checkSum()
+}
+
+func treeReduce512(leaves [][Size512]byte) [Size512]byte {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	k := treeSplit(len(leaves))
+	l, r := treeReduce512(leaves[:k]), treeReduce512(leaves[k:])
+	d := treeNode512
+	d.Write(l[:])
+	d.Write(r[:])
+	return d.checkSum()
+}
+
+func treeFinal512(size int64, leafSize int, tree [Size512]byte) [Size512]byte {
+	d := treeRoot512
+	d.Write(treeParams(size, leafSize))
+	d.Write(tree[:])
+	return d.checkSum()
+}
+
+// readLeaves reads every leaf of the first size bytes of r using the
+// configured number of goroutines and calls fn with the index and
+// contents of each. fn may be called concurrently.
+func readLeaves(r io.ReaderAt, size int64, leafSize int, cfg *TreeConfig, fn func(i int64, p []byte)) error {
+	n := treeLeaves(size, leafSize)
+	workers := cfg.workers()
+	if int64(workers) > n {
+		workers = int(n)
+	}
+
+	var (
+		next     int64 = -1
+		failed   int32
+		errOnce  sync.Once
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, leafSize)
+			for atomic.LoadInt32(&failed) == 0 {
+				i := atomic.AddInt64(&next, 1)
+				if i >= n {
+					return
+				}
+				off := i * int64(leafSize)
+				p := buf
+				if rem := size - off; rem < int64(len(p)) {
+					p = p[:rem]
+				}
+				if k, err := r.ReadAt(p, off); k < len(p) {
+					if err == nil || err == io.EOF {
+						err = io.ErrUnexpectedEOF
+					}
+					errOnce.Do(func() { firstErr = err })
+					atomic.StoreInt32(&failed, 1)
+					return
+				}
+				fn(i, p)
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// TreeSum256 returns the BLAKE-256 tree mode checksum of the first
+// size bytes of r, reading and hashing leaves in parallel. A nil cfg
+// uses the defaults.
+func TreeSum256(r io.ReaderAt, size int64, cfg *TreeConfig) ([Size256]byte, error) {
+	leafSize, err := cfg.leafSize(size)
+	if err != nil {
+		return [Size256]byte{}, err
+	}
+	leaves := make([][Size256]byte, treeLeaves(size, leafSize))
+	err = readLeaves(r, size, leafSize, cfg, func(i int64, p []byte) {
+		leaves[i] = treeLeafHash256(p)
+	})
+	if err != nil {
+		return [Size256]byte{}, err
+	}
+	return treeFinal256(size, leafSize, treeReduce256(leaves)), nil
+}
+
+// TreeSum512 returns the BLAKE-512 tree mode checksum of the first
+// size bytes of r, reading and hashing leaves in parallel. A nil cfg
+// uses the defaults.
+func TreeSum512(r io.ReaderAt, size int64, cfg *TreeConfig) ([Size512]byte, error) {
+	leafSize, err := cfg.leafSize(size)
+	if err != nil {
+		return [Size512]byte{}, err
+	}
+	leaves := make([][Size512]byte, treeLeaves(size, leafSize))
+	err = readLeaves(r, size, leafSize, cfg, func(i int64, p []byte) {
+		leaves[i] = treeLeafHash512(p)
+	})
+	if err != nil {
+		return [Size512]byte{}, err
+	}
+	return treeFinal512(size, leafSize, treeReduce512(leaves)), nil
+}
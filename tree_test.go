@@ -0,0 +1,87 @@
+package blake
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// refTreeSum256 computes the tree mode checksum sequentially with the
+// salted one-shot functions.
+func refTreeSum256(data []byte, leafSize int) [Size256]byte {
+	var nodes [][Size256]byte
+	for i := 0; i == 0 || i < len(data); i += leafSize {
+		end := i + leafSize
+		if end > len(data) {
+			end = len(data)
+		}
+		nodes = append(nodes, Sum256withSalt(data[i:end], treeLeafSalt[:16]))
+	}
+	var reduce func(n [][Size256]byte) [Size256]byte
+	reduce = func(n [][Size256]byte) [Size256]byte {
+		if len(n) == 1 {
+			return n[0]
+		}
+		k := treeSplit(len(n))
+		l, r := reduce(n[:k]), reduce(n[k:])
+		return Sum256withSalt(append(l[:], r[:]...), treeNodeSalt[:16])
+	}
+	tree := reduce(nodes)
+	var params [16]byte
+	binary.BigEndian.PutUint64(params[:], uint64(len(data)))
+	binary.BigEndian.PutUint64(params[8:], uint64(leafSize))
+	return Sum256withSalt(append(params[:], tree[:]...), treeRootSalt[:16])
+}
+
+func TestTreeSum256(t *testing.T) {
+	data := make([]byte, 10000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	for _, n := range []int{0, 1, 100, 1000, 10000} {
+		want := refTreeSum256(data[:n], 100)
+		for _, workers := range []int{1, 2, 7, 0} {
+			cfg := &TreeConfig{LeafSize: 100, Workers: workers}
+			got, err := TreeSum256(bytes.NewReader(data), int64(n), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("size %d, %d workers: expected %x, got %x", n, workers, want, got)
+			}
+		}
+	}
+	a, _ := TreeSum256(bytes.NewReader(data), 1000, &TreeConfig{LeafSize: 100})
+	b, _ := TreeSum256(bytes.NewReader(data), 1000, &TreeConfig{LeafSize: 200})
+	if a == b {
+		t.Error("checksum does not depend on the leaf size")
+	}
+}
+
+func TestTreeSum512(t *testing.T) {
+	data := make([]byte, 3000)
+	var sums [][Size512]byte
+	for _, workers := range []int{1, 3, 16} {
+		sum, err := TreeSum512(bytes.NewReader(data), int64(len(data)), &TreeConfig{LeafSize: 128, Workers: workers})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sums = append(sums, sum)
+	}
+	if sums[0] != sums[1] || sums[1] != sums[2] {
+		t.Error("checksum depends on the number of workers")
+	}
+	if sum, _ := TreeSum512(bytes.NewReader(nil), 0, nil); sum == sums[0] {
+		t.Error("empty input has the same checksum as non-empty input")
+	}
+}
+
+func TestTreeSumErrors(t *testing.T) {
+	if _, err := TreeSum256(bytes.NewReader(make([]byte, 10)), 20, &TreeConfig{LeafSize: 4}); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	if _, err := TreeSum256(bytes.NewReader(nil), 0, &TreeConfig{LeafSize: -1}); err == nil {
+		t.Error("expected error for negative leaf size")
+	}
+}
@@ -0,0 +1,258 @@
+package blake
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrWriteRange is returned by TreeWriterAt.WriteAt for data
+	// outside the declared length.
+	ErrWriteRange = errors.New("blake: write outside the declared length")
+
+	// ErrOverlap is returned by TreeWriterAt.WriteAt when a range
+	// is written more than once.
+	ErrOverlap = errors.New("blake: overlapping write")
+
+	// ErrIncomplete is returned by TreeWriterAt.Sum when some bytes
+	// have not been written.
+	ErrIncomplete = errors.New("blake: missing data")
+)
+
+// TreeWriterAt computes the tree mode checksum of content of a known
+// length which is written out of order, possibly from many goroutines.
+// Every leaf is hashed by the goroutine which completes it and its
+// buffer is released, so only partially written leaves are held in
+// memory.
+type TreeWriterAt struct {
+	size     int64
+	leafSize int
+	hashSize int
+	leafHash func(p, out []byte)
+
+	mu      sync.Mutex
+	pending map[int64]*treePendingLeaf
+	done    []bool
+	ndone   int64
+	sums    []byte
+	err     error
+}
+
+type treePendingLeaf struct {
+	mu      sync.Mutex
+	buf     []byte
+	written [][2]int // sorted, non-overlapping [start, end) ranges
+	n       int
+	done    bool
+}
+
+// NewTreeWriterAt256 returns a TreeWriterAt computing the BLAKE-256
+// tree mode checksum of size bytes. cfg.Workers is ignored.
+func NewTreeWriterAt256(size int64, cfg *TreeConfig) (*TreeWriterAt, error) {
+	return newTreeWriterAt(size, cfg, Size256, func(p, out []byte) {
+		sum := treeLeafHash256(p)
+		copy(out, sum[:])
+	})
+}
+
+// NewTreeWriterAt512 returns a TreeWriterAt computing the BLAKE-512
+// tree mode checksum of size bytes. cfg.Workers is ignored.
+func NewTreeWriterAt512(size int64, cfg *TreeConfig) (*TreeWriterAt, error) {
+	return newTreeWriterAt(size, cfg, Size512, func(p, out []byte) {
+		sum := treeLeafHash512(p)
+		copy(out, sum[:])
+	})
+}
+
+func newTreeWriterAt(size int64, cfg *TreeConfig, hashSize int, leafHash func(p, out []byte)) (*TreeWriterAt, error) {
+	leafSize, err := cfg.leafSize(size)
+	if err != nil {
+		return nil, err
+	}
+	n := treeLeaves(size, leafSize)
+	w := &TreeWriterAt{
+		size:     size,
+		leafSize: leafSize,
+		hashSize: hashSize,
+		leafHash: leafHash,
+		pending:  make(map[int64]*treePendingLeaf),
+		done:     make([]bool, n),
+		sums:     make([]byte, n*int64(hashSize)),
+	}
+	if size == 0 {
+		leafHash(nil, w.sums)
+		w.done[0] = true
+		w.ndone = 1
+	}
+	return w, nil
+}
+
+// leafLen returns the length of leaf i.
+func (w *TreeWriterAt) leafLen(i int64) int {
+	if rem := w.size - i*int64(w.leafSize); rem < int64(w.leafSize) {
+		return int(rem)
+	}
+	return w.leafSize
+}
+
+// WriteAt records p at offset off. It returns ErrWriteRange if the
+// range lies outside the declared length and ErrOverlap if any part of
+// it was written before. ErrOverlap is sticky and is also returned by
+// later calls and by Sum.
+func (w *TreeWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 || off > w.size || int64(len(p)) > w.size-off {
+		return 0, ErrWriteRange
+	}
+	written := 0
+	for len(p) > 0 {
+		i := off / int64(w.leafSize)
+		start := int(off - i*int64(w.leafSize))
+		n := w.leafLen(i) - start
+		if n > len(p) {
+			n = len(p)
+		}
+		if err := w.writeLeaf(i, start, p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+		off += int64(n)
+	}
+	return written, nil
+}
+
+func (w *TreeWriterAt) fail(err error) error {
+	w.mu.Lock()
+	if w.err == nil {
+		w.err = err
+	}
+	w.mu.Unlock()
+	return err
+}
+
+// writeLeaf copies p to offset start of leaf i and hashes the leaf if
+// it is complete.
+func (w *TreeWriterAt) writeLeaf(i int64, start int, p []byte) error {
+	w.mu.Lock()
+	if w.err != nil {
+		err := w.err
+		w.mu.Unlock()
+		return err
+	}
+	if w.done[i] {
+		w.mu.Unlock()
+		return w.fail(fmt.Errorf("%w at offset %d", ErrOverlap, i*int64(w.leafSize)+int64(start)))
+	}
+	l := w.pending[i]
+	if l == nil {
+		l = &treePendingLeaf{buf: make([]byte, w.leafLen(i))}
+		w.pending[i] = l
+	}
+	w.mu.Unlock()
+
+	l.mu.Lock()
+	end := start + len(p)
+	j := sort.Search(len(l.written), func(j int) bool { return l.written[j][1] > start })
+	if l.done || j < len(l.written) && l.written[j][0] < end {
+		l.mu.Unlock()
+		return w.fail(fmt.Errorf("%w at offset %d", ErrOverlap, i*int64(w.leafSize)+int64(start)))
+	}
+	copy(l.buf[start:], p)
+	l.written = append(l.written, [2]int{})
+	copy(l.written[j+1:], l.written[j:])
+	l.written[j] = [2]int{start, end}
+	l.n += len(p)
+	if l.n < len(l.buf) {
+		l.mu.Unlock()
+		return nil
+	}
+	l.done = true
+	buf := l.buf
+	l.buf = nil
+	l.mu.Unlock()
+
+	var sum [Size512]byte
+	w.leafHash(buf, sum[:w.hashSize])
+	w.mu.Lock()
+	copy(w.sums[i*int64(w.hashSize):], sum[:w.hashSize])
+	w.done[i] = true
+	w.ndone++
+	delete(w.pending, i)
+	w.mu.Unlock()
+	return nil
+}
+
+// Missing returns the [start, end) byte ranges which have not been
+// written yet, in order.
+func (w *TreeWriterAt) Missing() [][2]int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var missing [][2]int64
+	add := func(start, end int64) {
+		if n := len(missing); n > 0 && missing[n-1][1] == start {
+			missing[n-1][1] = end
+		} else {
+			missing = append(missing, [2]int64{start, end})
+		}
+	}
+	for i, done := range w.done {
+		if done {
+			continue
+		}
+		base := int64(i) * int64(w.leafSize)
+		pos := 0
+		if l := w.pending[int64(i)]; l != nil {
+			l.mu.Lock()
+			for _, r := range l.written {
+				if r[0] > pos {
+					add(base+int64(pos), base+int64(r[0]))
+				}
+				pos = r[1]
+			}
+			l.mu.Unlock()
+		}
+		if end := w.leafLen(int64(i)); pos < end {
+			add(base+int64(pos), base+int64(end))
+		}
+	}
+	return missing
+}
+
+// Sum returns the tree mode checksum once every byte up to the
+// declared length has been written. Otherwise it returns an error
+// wrapping ErrIncomplete which reports the first missing range.
+func (w *TreeWriterAt) Sum() ([]byte, error) {
+	w.mu.Lock()
+	err, complete := w.err, w.ndone == int64(len(w.done))
+	w.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if !complete {
+		m := w.Missing()
+		if len(m) == 0 {
+			// A concurrent WriteAt is still hashing the last leaf.
+			return nil, ErrIncomplete
+		}
+		return nil, fmt.Errorf("%w: %d ranges, first at [%d, %d)", ErrIncomplete, len(m), m[0][0], m[0][1])
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.hashSize == Size256 {
+		leaves := make([][Size256]byte, len(w.done))
+		for i := range leaves {
+			copy(leaves[i][:], w.sums[i*Size256:])
+		}
+		sum := treeFinal256(w.size, w.leafSize, treeReduce256(leaves))
+		return sum[:], nil
+	}
+	leaves := make([][Size512]byte, len(w.done))
+	for i := range leaves {
+		copy(leaves[i][:], w.sums[i*Size512:])
+	}
+	sum := treeFinal512(w.size, w.leafSize, treeReduce512(leaves))
+	return sum[:], nil
+}
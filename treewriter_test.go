@@ -0,0 +1,102 @@
+package blake
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func TestTreeWriterAt(t *testing.T) {
+	data := make([]byte, 5000)
+	rand.New(rand.NewSource(1)).Read(data)
+	cfg := &TreeConfig{LeafSize: 256}
+	for _, size := range []int{0, 1, 256, 5000} {
+		want256, _ := TreeSum256(bytes.NewReader(data), int64(size), cfg)
+		want512, _ := TreeSum512(bytes.NewReader(data), int64(size), cfg)
+
+		w256, err := NewTreeWriterAt256(int64(size), cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w512, _ := NewTreeWriterAt512(int64(size), cfg)
+
+		// Write ranges of varying length in random order from
+		// several goroutines.
+		var ranges [][2]int
+		for off := 0; off < size; {
+			n := 1 + off%300
+			if off+n > size {
+				n = size - off
+			}
+			ranges = append(ranges, [2]int{off, off + n})
+			off += n
+		}
+		rand.Shuffle(len(ranges), func(i, j int) { ranges[i], ranges[j] = ranges[j], ranges[i] })
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := g; i < len(ranges); i += 4 {
+					r := ranges[i]
+					if _, err := w256.WriteAt(data[r[0]:r[1]], int64(r[0])); err != nil {
+						t.Error(err)
+					}
+					if _, err := w512.WriteAt(data[r[0]:r[1]], int64(r[0])); err != nil {
+						t.Error(err)
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+
+		if sum, err := w256.Sum(); err != nil || !bytes.Equal(sum, want256[:]) {
+			t.Errorf("size %d: BLAKE-256 sum %x, %v; want %x", size, sum, err, want256)
+		}
+		if sum, err := w512.Sum(); err != nil || !bytes.Equal(sum, want512[:]) {
+			t.Errorf("size %d: BLAKE-512 sum %x, %v; want %x", size, sum, err, want512)
+		}
+	}
+}
+
+func TestTreeWriterAtMissing(t *testing.T) {
+	w, _ := NewTreeWriterAt256(1000, &TreeConfig{LeafSize: 100})
+	w.WriteAt(make([]byte, 150), 0)
+	w.WriteAt(make([]byte, 50), 180)
+	w.WriteAt(make([]byte, 10), 990)
+	if got := fmt.Sprint(w.Missing()); got != "[[150 180] [230 990]]" {
+		t.Errorf("unexpected missing ranges %s", got)
+	}
+	if _, err := w.Sum(); !errors.Is(err, ErrIncomplete) {
+		t.Errorf("expected ErrIncomplete, got %v", err)
+	}
+}
+
+func TestTreeWriterAtErrors(t *testing.T) {
+	w, _ := NewTreeWriterAt256(1000, &TreeConfig{LeafSize: 100})
+	if _, err := w.WriteAt(make([]byte, 10), 995); err != ErrWriteRange {
+		t.Errorf("expected ErrWriteRange, got %v", err)
+	}
+	if _, err := w.WriteAt(make([]byte, 10), -1); err != ErrWriteRange {
+		t.Errorf("expected ErrWriteRange, got %v", err)
+	}
+	if _, err := w.WriteAt(make([]byte, 50), 20); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.WriteAt(make([]byte, 10), 65); !errors.Is(err, ErrOverlap) {
+		t.Errorf("expected ErrOverlap, got %v", err)
+	}
+	if _, err := w.Sum(); !errors.Is(err, ErrOverlap) {
+		t.Errorf("expected sticky ErrOverlap, got %v", err)
+	}
+
+	// Rewriting a leaf which has already been hashed also overlaps.
+	w, _ = NewTreeWriterAt256(200, &TreeConfig{LeafSize: 100})
+	w.WriteAt(make([]byte, 100), 0)
+	if _, err := w.WriteAt(make([]byte, 1), 99); !errors.Is(err, ErrOverlap) {
+		t.Errorf("expected ErrOverlap, got %v", err)
+	}
+}
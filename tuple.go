@@ -0,0 +1,93 @@
+package blake
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// TupleElement is an element of a Tuple: either Bytes or a nested
+// Tuple.
+type TupleElement interface {
+	writeTuple(w io.Writer)
+}
+
+// Bytes is a byte string element of a Tuple.
+type Bytes []byte
+
+// Tuple is a sequence of elements hashed unambiguously by TupleSum256
+// and TupleSum512.
+//
+// Every element is framed with a type tag and its length, so tuples
+// with the same concatenated contents but different boundaries or
+// nesting hash differently. The encoding is
+//
+//	enc(Bytes b) = 0x00 || uint64(len(b)) || b
+//	enc(Tuple t) = 0x01 || uint64(len(t)) || enc(t[0]) || ... || enc(t[n-1])
+//
+// with lengths in big-endian order, counting bytes for Bytes and
+// elements for a Tuple. A nil element is encoded as empty Bytes. The
+// checksum is the BLAKE-256 or BLAKE-512 hash of enc(t) salted with the
+// first 16 or 32 bytes of
+//
+//	BLAKE-512("blake tuple customization\x00" || custom)
+//
+// or unsalted if the customization string is empty.
+type Tuple []TupleElement
+
+const (
+	tupleBytesTag = 0x00
+	tupleTag      = 0x01
+)
+
+func writeTupleHeader(w io.Writer, tag byte, n int) {
+	var hdr [9]byte
+	hdr[0] = tag
+	binary.BigEndian.PutUint64(hdr[1:], uint64(n))
+	w.Write(hdr[:])
+}
+
+func (b Bytes) writeTuple(w io.Writer) {
+	writeTupleHeader(w, tupleBytesTag, len(b))
+	w.Write(b)
+}
+
+func (t Tuple) writeTuple(w io.Writer) {
+	writeTupleHeader(w, tupleTag, len(t))
+	for _, e := range t {
+		if e == nil {
+			e = Bytes(nil)
+		}
+		e.writeTuple(w)
+	}
+}
+
+// tupleSalt returns the salt of n bytes for a customization string.
+// The empty string maps to the all-zero salt, so an uncustomized tuple
+// hash is a plain BLAKE hash of the encoding.
+func tupleSalt(custom string, n int) []byte {
+	if custom == "" {
+		return nil
+	}
+	sum := Sum512(append([]byte("blake tuple customization\x00"), custom...))
+	return sum[:n]
+}
+
+// TupleSum256 returns the BLAKE-256 checksum of the encoding of t with
+// the given customization string.
+func TupleSum256(t Tuple, custom string) [Size256]byte {
+	var d digest256
+	d.setSalt(tupleSalt(custom, 16))
+	d.Reset()
+	t.writeTuple(&d)
+	return d.checkSum()
+}
+
+// TupleSum512 returns the BLAKE-512 checksum of the encoding of t with
+// the given customization string.
+func TupleSum512(t Tuple, custom string) [Size512]byte {
+	var d digest512
+	d.setSalt(tupleSalt(custom, 32))
+	d.Reset()
+	t.writeTuple(&d)
+	return d.checkSum()
+}
@@ -0,0 +1,89 @@
+package blake
+
+import (
+	"fmt"
+	"testing"
+)
+
+var tupleVectors256 = []struct {
+	out    string
+	in     Tuple
+	custom string
+}{
+	{"7dcfc1bad77852845e9e2863e91f3ef96b38608735e7724b34f489bcb669814e",
+		Tuple{}, ""},
+	{"05779b403f286262369d9f06e3dcf5b4bcde6110096c785e6917340c77638ab3",
+		Tuple{Bytes("ab"), Bytes("c")}, ""},
+	{"33c549529db59bba51a72ba08a596cc57db12cafee46dfa1db1868c0a77ecdba",
+		Tuple{Bytes("ab"), Bytes("c")}, "My Tuple App"},
+	{"b729f1c9c74901f3610d1918ad940d45f1f49be248ed0e0ad33463bbdb198cbd",
+		Tuple{Bytes("a"), Tuple{Bytes("b"), Bytes("")}}, "My Tuple App"},
+}
+
+var tupleVectors512 = []struct {
+	out    string
+	in     Tuple
+	custom string
+}{
+	{"7599d6863bfd80f0a98e6f75bd11be00e41ddd3a9aac8294eb3ea2ca1f58d538df489dfd87450057185ab4662e628a5259e5ce4058b1ede0e7bd989b5d9f8dd6",
+		Tuple{Bytes("ab"), Bytes("c")}, ""},
+	{"c9bd7f568a0cf8c75a4a4a174827282a86e7df6e00ca8bf1d65d1cf06e8a564cdabf744245ae719166ab9655c62ad34802a8c751da322ec55536c91eaa439794",
+		Tuple{Bytes("a"), Tuple{Bytes("b"), Bytes("")}}, "My Tuple App"},
+}
+
+func TestTupleSum(t *testing.T) {
+	for i, v := range tupleVectors256 {
+		if res := fmt.Sprintf("%x", TupleSum256(v.in, v.custom)); res != v.out {
+			t.Errorf("256 %d: expected %q, got %q", i, v.out, res)
+		}
+	}
+	for i, v := range tupleVectors512 {
+		if res := fmt.Sprintf("%x", TupleSum512(v.in, v.custom)); res != v.out {
+			t.Errorf("512 %d: expected %q, got %q", i, v.out, res)
+		}
+	}
+}
+
+func TestTupleEncoding(t *testing.T) {
+	enc := []byte{
+		0x01, 0, 0, 0, 0, 0, 0, 0, 2,
+		0x00, 0, 0, 0, 0, 0, 0, 0, 1, 'a',
+		0x01, 0, 0, 0, 0, 0, 0, 0, 1,
+		0x00, 0, 0, 0, 0, 0, 0, 0, 2, 'b', 'c',
+	}
+	in := Tuple{Bytes("a"), Tuple{Bytes("bc")}}
+	if TupleSum256(in, "") != Sum256(enc) {
+		t.Error("uncustomized tuple hash differs from Sum256 of the encoding")
+	}
+	if TupleSum256(in, "ctx") != Sum256withSalt(enc, tupleSalt("ctx", 16)) {
+		t.Error("customized tuple hash differs from the salted hash of the encoding")
+	}
+	if TupleSum512(in, "ctx") != Sum512withSalt(enc, tupleSalt("ctx", 32)) {
+		t.Error("customized tuple hash differs from the salted hash of the encoding")
+	}
+	if TupleSum256(Tuple{nil, Tuple{nil}}, "") != TupleSum256(Tuple{Bytes{}, Tuple{Bytes{}}}, "") {
+		t.Error("nil element is not hashed as empty Bytes")
+	}
+}
+
+func TestTupleAmbiguity(t *testing.T) {
+	tuples := []Tuple{
+		{Bytes("ab"), Bytes("c")},
+		{Bytes("a"), Bytes("bc")},
+		{Bytes("abc")},
+		{Tuple{Bytes("abc")}},
+		{Bytes("abc"), Bytes("")},
+		{Bytes("abc"), Tuple{}},
+	}
+	seen := make(map[[Size256]byte]int)
+	for i, tu := range tuples {
+		sum := TupleSum256(tu, "")
+		if j, ok := seen[sum]; ok {
+			t.Errorf("tuples %d and %d have the same checksum", j, i)
+		}
+		seen[sum] = i
+	}
+	if TupleSum256(tuples[0], "a") == TupleSum256(tuples[0], "b") {
+		t.Error("customization string does not change the checksum")
+	}
+}
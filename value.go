@@ -0,0 +1,188 @@
+package blake
+
+import (
+	"crypto/subtle"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// Digest224Value is a BLAKE-224 checksum which encodes as lowercase hex
+// in text and JSON and as raw bytes in SQL databases.
+type Digest224Value [Size224]byte
+
+// Digest256Value is a BLAKE-256 checksum which encodes as lowercase hex
+// in text and JSON and as raw bytes in SQL databases.
+type Digest256Value [Size256]byte
+
+// Digest384Value is a BLAKE-384 checksum which encodes as lowercase hex
+// in text and JSON and as raw bytes in SQL databases.
+type Digest384Value [Size384]byte
+
+// Digest512Value is a BLAKE-512 checksum which encodes as lowercase hex
+// in text and JSON and as raw bytes in SQL databases.
+type Digest512Value [Size512]byte
+
+func parseHex(dst []byte, s string) error {
+	if len(s) != hex.EncodedLen(len(dst)) {
+		return fmt.Errorf("blake: hex digest has length %d, want %d", len(s), hex.EncodedLen(len(dst)))
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err
+}
+
+var base64Encodings = []*base64.Encoding{
+	base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding,
+}
+
+// parseBase64 accepts standard and URL-safe base64, with or without
+// padding.
+func parseBase64(dst []byte, s string) error {
+	for _, enc := range base64Encodings {
+		if b, err := enc.DecodeString(s); err == nil {
+			if len(b) != len(dst) {
+				return fmt.Errorf("blake: base64 digest has %d bytes, want %d", len(b), len(dst))
+			}
+			copy(dst, b)
+			return nil
+		}
+	}
+	return fmt.Errorf("blake: invalid base64 digest %q", s)
+}
+
+// scanDigest accepts raw bytes or a hex string from a database.
+func scanDigest(dst []byte, src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		if len(v) == len(dst) {
+			copy(dst, v)
+			return nil
+		}
+		return parseHex(dst, string(v))
+	case string:
+		return parseHex(dst, v)
+	case nil:
+		return fmt.Errorf("blake: cannot scan NULL into a digest")
+	}
+	return fmt.Errorf("blake: cannot scan %T into a digest", src)
+}
+
+// ParseDigest224Hex parses a hex-encoded BLAKE-224 checksum.
+func ParseDigest224Hex(s string) (d Digest224Value, err error) {
+	err = parseHex(d[:], s)
+	return
+}
+
+// ParseDigest256Hex parses a hex-encoded BLAKE-256 checksum.
+func ParseDigest256Hex(s string) (d Digest256Value, err error) {
+	err = parseHex(d[:], s)
+	return
+}
+
+// ParseDigest384Hex parses a hex-encoded BLAKE-384 checksum.
+func ParseDigest384Hex(s string) (d Digest384Value, err error) {
+	err = parseHex(d[:], s)
+	return
+}
+
+// ParseDigest512Hex parses a hex-encoded BLAKE-512 checksum.
+func ParseDigest512Hex(s string) (d Digest512Value, err error) {
+	err = parseHex(d[:], s)
+	return
+}
+
+// ParseDigest224Base64 parses a base64-encoded BLAKE-224 checksum.
+func ParseDigest224Base64(s string) (d Digest224Value, err error) {
+	err = parseBase64(d[:], s)
+	return
+}
+
+// ParseDigest256Base64 parses a base64-encoded BLAKE-256 checksum.
+func ParseDigest256Base64(s string) (d Digest256Value, err error) {
+	err = parseBase64(d[:], s)
+	return
+}
+
+// ParseDigest384Base64 parses a base64-encoded BLAKE-384 checksum.
+func ParseDigest384Base64(s string) (d Digest384Value, err error) {
+	err = parseBase64(d[:], s)
+	return
+}
+
+// ParseDigest512Base64 parses a base64-encoded BLAKE-512 checksum.
+func ParseDigest512Base64(s string) (d Digest512Value, err error) {
+	err = parseBase64(d[:], s)
+	return
+}
+
+func (d Digest224Value) String() string { return hex.EncodeToString(d[:]) }
+func (d Digest256Value) String() string { return hex.EncodeToString(d[:]) }
+func (d Digest384Value) String() string { return hex.EncodeToString(d[:]) }
+func (d Digest512Value) String() string { return hex.EncodeToString(d[:]) }
+
+// Base64 returns the standard padded base64 encoding of d.
+func (d Digest224Value) Base64() string { return base64.StdEncoding.EncodeToString(d[:]) }
+
+// Base64 returns the standard padded base64 encoding of d.
+func (d Digest256Value) Base64() string { return base64.StdEncoding.EncodeToString(d[:]) }
+
+// Base64 returns the standard padded base64 encoding of d.
+func (d Digest384Value) Base64() string { return base64.StdEncoding.EncodeToString(d[:]) }
+
+// Base64 returns the standard padded base64 encoding of d.
+func (d Digest512Value) Base64() string { return base64.StdEncoding.EncodeToString(d[:]) }
+
+// Equal reports whether d and o are equal in constant time.
+func (d Digest224Value) Equal(o Digest224Value) bool {
+	return subtle.ConstantTimeCompare(d[:], o[:]) == 1
+}
+
+// Equal reports whether d and o are equal in constant time.
+func (d Digest256Value) Equal(o Digest256Value) bool {
+	return subtle.ConstantTimeCompare(d[:], o[:]) == 1
+}
+
+// Equal reports whether d and o are equal in constant time.
+func (d Digest384Value) Equal(o Digest384Value) bool {
+	return subtle.ConstantTimeCompare(d[:], o[:]) == 1
+}
+
+// Equal reports whether d and o are equal in constant time.
+func (d Digest512Value) Equal(o Digest512Value) bool {
+	return subtle.ConstantTimeCompare(d[:], o[:]) == 1
+}
+
+func (d Digest224Value) MarshalText() ([]byte, error) { return []byte(d.String()), nil }
+func (d Digest256Value) MarshalText() ([]byte, error) { return []byte(d.String()), nil }
+func (d Digest384Value) MarshalText() ([]byte, error) { return []byte(d.String()), nil }
+func (d Digest512Value) MarshalText() ([]byte, error) { return []byte(d.String()), nil }
+
+func (d *Digest224Value) UnmarshalText(b []byte) error { return parseHex(d[:], string(b)) }
+func (d *Digest256Value) UnmarshalText(b []byte) error { return parseHex(d[:], string(b)) }
+func (d *Digest384Value) UnmarshalText(b []byte) error { return parseHex(d[:], string(b)) }
+func (d *Digest512Value) UnmarshalText(b []byte) error { return parseHex(d[:], string(b)) }
+
+// Value implements driver.Valuer, storing the raw bytes.
+func (d Digest224Value) Value() (driver.Value, error) { return d[:], nil }
+
+// Value implements driver.Valuer, storing the raw bytes.
+func (d Digest256Value) Value() (driver.Value, error) { return d[:], nil }
+
+// Value implements driver.Valuer, storing the raw bytes.
+func (d Digest384Value) Value() (driver.Value, error) { return d[:], nil }
+
+// Value implements driver.Valuer, storing the raw bytes.
+func (d Digest512Value) Value() (driver.Value, error) { return d[:], nil }
+
+// Scan implements sql.Scanner, accepting raw bytes or a hex string.
+func (d *Digest224Value) Scan(src interface{}) error { return scanDigest(d[:], src) }
+
+// Scan implements sql.Scanner, accepting raw bytes or a hex string.
+func (d *Digest256Value) Scan(src interface{}) error { return scanDigest(d[:], src) }
+
+// Scan implements sql.Scanner, accepting raw bytes or a hex string.
+func (d *Digest384Value) Scan(src interface{}) error { return scanDigest(d[:], src) }
+
+// Scan implements sql.Scanner, accepting raw bytes or a hex string.
+func (d *Digest512Value) Scan(src interface{}) error { return scanDigest(d[:], src) }
@@ -0,0 +1,92 @@
+package blake
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var (
+	_ encoding.TextMarshaler   = Digest256Value{}
+	_ encoding.TextUnmarshaler = (*Digest256Value)(nil)
+	_ driver.Valuer            = Digest512Value{}
+	_ sql.Scanner              = (*Digest512Value)(nil)
+)
+
+func TestDigestValueText(t *testing.T) {
+	d := Digest256Value(Sum256([]byte("BLAKE")))
+	if d.String() != vectors256[1].out {
+		t.Errorf("String() = %s, want %s", d, vectors256[1].out)
+	}
+	p, err := ParseDigest256Hex(vectors256[1].out)
+	if err != nil || !p.Equal(d) {
+		t.Errorf("ParseDigest256Hex = %s, %v", p, err)
+	}
+	if _, err := ParseDigest256Hex(vectors224[1].out); err == nil {
+		t.Error("expected error for BLAKE-224 hex parsed as BLAKE-256")
+	}
+	if _, err := ParseDigest256Hex(strings.Repeat("zz", Size256)); err == nil {
+		t.Error("expected error for invalid hex")
+	}
+
+	d224 := Digest224Value(Sum224([]byte("BLAKE")))
+	p224, err := ParseDigest224Base64(d224.Base64())
+	if err != nil || p224 != d224 {
+		t.Errorf("ParseDigest224Base64 = %s, %v", p224, err)
+	}
+	raw := strings.TrimRight(d224.Base64(), "=")
+	if p224, err = ParseDigest224Base64(raw); err != nil || p224 != d224 {
+		t.Errorf("ParseDigest224Base64 without padding = %s, %v", p224, err)
+	}
+	if _, err := ParseDigest384Base64(d224.Base64()); err == nil {
+		t.Error("expected error for BLAKE-224 base64 parsed as BLAKE-384")
+	}
+}
+
+func TestDigestValueJSON(t *testing.T) {
+	type record struct {
+		Sum384 Digest384Value
+		Sum512 Digest512Value
+	}
+	in := record{Digest384Value(Sum384([]byte("Golang"))), Digest512Value(Sum512([]byte("Golang")))}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"Sum384":"` + vectors384[3].out + `","Sum512":"` + vectors512[3].out + `"}`
+	if string(b) != want {
+		t.Errorf("json.Marshal = %s, want %s", b, want)
+	}
+	var out record
+	if err := json.Unmarshal(b, &out); err != nil || out != in {
+		t.Errorf("json.Unmarshal = %+v, %v", out, err)
+	}
+	if err := json.Unmarshal([]byte(`{"Sum384":"abcd"}`), &out); err == nil {
+		t.Error("expected error for short digest in JSON")
+	}
+}
+
+func TestDigestValueSQL(t *testing.T) {
+	d := Digest512Value(Sum512([]byte("ube")))
+	v, err := d.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s Digest512Value
+	if err := s.Scan(v); err != nil || s != d {
+		t.Errorf("Scan(raw) = %s, %v", s, err)
+	}
+	s = Digest512Value{}
+	if err := s.Scan(d.String()); err != nil || s != d {
+		t.Errorf("Scan(hex) = %s, %v", s, err)
+	}
+	if err := s.Scan(nil); err == nil {
+		t.Error("expected error scanning NULL")
+	}
+	if err := s.Scan(42); err == nil {
+		t.Error("expected error scanning an integer")
+	}
+}
@@ -0,0 +1,468 @@
+// Package verifiedstream implements a verified streaming format in
+// which content is split into fixed-size chunks arranged in a BLAKE-256
+// Merkle tree, so that a receiver can detect corruption chunk by chunk
+// instead of only at the end of the stream.
+//
+// The tree has the shape of an RFC 6962 Merkle tree over the chunks:
+// leaves are blake.MerkleLeafHash(chunk) and parents are
+// blake.MerkleNodeHash(left, right), with the left subtree holding the
+// largest power of two number of chunks. The root digest binds the
+// content length to the tree hash:
+//
+//	root = BLAKE-256-salt(uint64(length) || tree hash)
+//
+// using the salt "verified stream\x00". Empty content has the tree
+// hash BLAKE-256("").
+//
+// Encodings start with the content length as a big-endian uint64. The
+// combined encoding then holds the tree in pre-order, every parent as
+// the 64-byte concatenation of its children's hashes and every leaf as
+// the chunk itself. The outboard encoding holds only the parents and
+// is used alongside the unmodified content.
+package verifiedstream
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/ouzklcn/blake"
+)
+
+// ChunkSize is the size of a chunk in bytes. The last chunk may be
+// shorter.
+const ChunkSize = 1024
+
+const (
+	headerSize = 8
+	parentSize = 2 * blake.Size256
+)
+
+// rootSalt is the BLAKE-256 salt used to finalize the root digest.
+const rootSalt = "verified stream\x00"
+
+// ErrCorrupt is returned when encoded data does not match the root.
+var ErrCorrupt = errors.New("verifiedstream: hash mismatch")
+
+// finalize returns the root digest for the given content length and
+// tree hash.
+func finalize(size uint64, tree [blake.Size256]byte) [blake.Size256]byte {
+	var b [headerSize + blake.Size256]byte
+	binary.BigEndian.PutUint64(b[:], size)
+	copy(b[headerSize:], tree[:])
+	return blake.Sum256withSalt(b[:], []byte(rootSalt))
+}
+
+// numChunks returns the number of chunks of content of the given size.
+func numChunks(size uint64) uint64 {
+	return (size + ChunkSize - 1) / ChunkSize
+}
+
+// chunkLen returns the length of the chunk with index i.
+func chunkLen(size, i uint64) uint64 {
+	if end := (i + 1) * ChunkSize; end > size {
+		return size - i*ChunkSize
+	}
+	return ChunkSize
+}
+
+// split returns the number of chunks in the left subtree of a tree
+// with n chunks. n must be greater than one.
+func split(n uint64) uint64 {
+	k := uint64(1)
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// encodedLen returns the size of the combined encoding of the subtree
+// of n chunks starting at chunk first, without the header.
+func encodedLen(size, first, n uint64) uint64 {
+	end := (first + n) * ChunkSize
+	if end > size {
+		end = size
+	}
+	return (n-1)*parentSize + end - first*ChunkSize
+}
+
+// offset returns the position of the subtree of n chunks starting at
+// chunk first in the combined encoding of content of the given size,
+// or in its outboard encoding if outboard is set.
+func offset(size, first, n uint64, outboard bool) uint64 {
+	off := uint64(headerSize)
+	f, m := uint64(0), numChunks(size)
+	for f != first || m != n {
+		k := split(m)
+		off += parentSize
+		if first < f+k {
+			m = k
+			continue
+		}
+		if outboard {
+			off += (k - 1) * parentSize
+		} else {
+			off += encodedLen(size, f, k)
+		}
+		f, m = f+k, m-k
+	}
+	return off
+}
+
+// subtree is a complete subtree on the stack of a hasher.
+type subtree struct {
+	hash     [blake.Size256]byte
+	first, n uint64
+}
+
+// hasher computes the tree hash of content added chunk by chunk. It
+// only keeps the roots of the complete subtrees, at most one per
+// power of two, and merges them as the tree grows.
+type hasher struct {
+	size  uint64
+	stack []subtree
+
+	// parent, if set, is called with every parent as soon as both of
+	// its children are known.
+	parent func(first, n uint64, l, r [blake.Size256]byte) error
+}
+
+func (h *hasher) merge() error {
+	l, r := h.stack[len(h.stack)-2], h.stack[len(h.stack)-1]
+	if h.parent != nil {
+		if err := h.parent(l.first, l.n+r.n, l.hash, r.hash); err != nil {
+			return err
+		}
+	}
+	h.stack = append(h.stack[:len(h.stack)-2], subtree{blake.MerkleNodeHash(l.hash, r.hash), l.first, l.n + r.n})
+	return nil
+}
+
+// add hashes the next chunk. Every chunk but the last must be full.
+func (h *hasher) add(chunk []byte) error {
+	h.stack = append(h.stack, subtree{blake.MerkleLeafHash(chunk), h.size / ChunkSize, 1})
+	h.size += uint64(len(chunk))
+	for len(h.stack) > 1 && h.stack[len(h.stack)-2].n == h.stack[len(h.stack)-1].n {
+		if err := h.merge(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// root merges the remaining subtrees and returns the root digest.
+func (h *hasher) root() ([blake.Size256]byte, error) {
+	if len(h.stack) == 0 {
+		return finalize(h.size, blake.Sum256(nil)), nil
+	}
+	for len(h.stack) > 1 {
+		if err := h.merge(); err != nil {
+			return [blake.Size256]byte{}, err
+		}
+	}
+	return finalize(h.size, h.stack[0].hash), nil
+}
+
+// chunks reads r to EOF and calls fn with every chunk.
+func chunks(r io.Reader, fn func([]byte) error) error {
+	buf := make([]byte, ChunkSize)
+	n := 0
+	for {
+		m, err := r.Read(buf[n:])
+		n += m
+		if n == ChunkSize || err == io.EOF && n > 0 {
+			if err := fn(buf[:n]); err != nil {
+				return err
+			}
+			n = 0
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Root reads r to EOF and returns the root digest of its content.
+func Root(r io.Reader) ([blake.Size256]byte, error) {
+	h := new(hasher)
+	if err := chunks(r, h.add); err != nil {
+		return [blake.Size256]byte{}, err
+	}
+	return h.root()
+}
+
+// Encode writes the combined encoding of the first size bytes of r to
+// w and returns the root digest. Content is read once and every chunk
+// and parent is written at its final position as soon as it is known.
+func Encode(w io.WriterAt, r io.Reader, size int64) ([blake.Size256]byte, error) {
+	return encodeAt(w, r, size, false)
+}
+
+// EncodeOutboard writes the outboard encoding of the first size bytes
+// of r to w and returns the root digest. Parents are written at their
+// final position as soon as they are known.
+func EncodeOutboard(w io.WriterAt, r io.Reader, size int64) ([blake.Size256]byte, error) {
+	return encodeAt(w, r, size, true)
+}
+
+var errNegativeSize = errors.New("verifiedstream: negative size")
+
+func encodeAt(w io.WriterAt, r io.Reader, size int64, outboard bool) ([blake.Size256]byte, error) {
+	if size < 0 {
+		return [blake.Size256]byte{}, errNegativeSize
+	}
+	var hdr [headerSize]byte
+	binary.BigEndian.PutUint64(hdr[:], uint64(size))
+	if _, err := w.WriteAt(hdr[:], 0); err != nil {
+		return [blake.Size256]byte{}, err
+	}
+	h := &hasher{parent: func(first, n uint64, l, r [blake.Size256]byte) error {
+		var b [parentSize]byte
+		copy(b[:], l[:])
+		copy(b[blake.Size256:], r[:])
+		_, err := w.WriteAt(b[:], int64(offset(uint64(size), first, n, outboard)))
+		return err
+	}}
+	err := chunks(io.LimitReader(r, size), func(c []byte) error {
+		if !outboard {
+			off := offset(uint64(size), h.size/ChunkSize, 1, false)
+			if _, err := w.WriteAt(c, int64(off)); err != nil {
+				return err
+			}
+		}
+		return h.add(c)
+	})
+	if err != nil {
+		return [blake.Size256]byte{}, err
+	}
+	if h.size != uint64(size) {
+		return [blake.Size256]byte{}, io.ErrUnexpectedEOF
+	}
+	return h.root()
+}
+
+// byteRange returns the bytes [lo, hi) of content of the given size
+// whose chunks are part of a slice starting at start with length n.
+// A slice always holds at least one chunk, so that it authenticates
+// the content length.
+func byteRange(size, start, n uint64) (lo, hi uint64) {
+	end := start + n
+	if end < start {
+		end = math.MaxUint64
+	}
+	if start < end && start < size {
+		if end > size {
+			end = size
+		}
+		return start, end
+	}
+	if start >= size {
+		start = size - 1
+	}
+	return start, start + 1
+}
+
+// overlaps reports whether the subtree of n chunks starting at first
+// overlaps the bytes [lo, hi).
+func overlaps(first, n, lo, hi uint64) bool {
+	return first*ChunkSize < hi && lo < (first+n)*ChunkSize
+}
+
+// ExtractSlice reads the combined encoding in enc and writes to w the
+// slice encoding of the n bytes starting at start. The slice encoding
+// is the header followed, in pre-order, by the parents and chunks of
+// every subtree which overlaps the requested range.
+func ExtractSlice(w io.Writer, enc io.ReaderAt, start, n uint64) error {
+	var hdr [headerSize]byte
+	if _, err := enc.ReadAt(hdr[:], 0); err != nil {
+		return err
+	}
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+	size := binary.BigEndian.Uint64(hdr[:])
+	if size == 0 {
+		return nil
+	}
+	lo, hi := byteRange(size, start, n)
+	buf := make([]byte, ChunkSize)
+	var extract func(off, first, n uint64) error
+	extract = func(off, first, n uint64) error {
+		if !overlaps(first, n, lo, hi) {
+			return nil
+		}
+		b := buf[:parentSize]
+		if n == 1 {
+			b = buf[:chunkLen(size, first)]
+		}
+		if _, err := enc.ReadAt(b, int64(off)); err != nil {
+			return err
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+		if n == 1 {
+			return nil
+		}
+		k := split(n)
+		off += parentSize
+		if err := extract(off, first, k); err != nil {
+			return err
+		}
+		return extract(off+encodedLen(size, first, k), first+k, n-k)
+	}
+	return extract(headerSize, 0, numChunks(size))
+}
+
+type pending struct {
+	hash     [blake.Size256]byte
+	first, n uint64
+	root     bool
+}
+
+// Decoder verifies an encoding against a root digest while reading it
+// and returns the content. Read returns ErrCorrupt as soon as a parent
+// or chunk fails to verify, so no unverified content is ever returned.
+type Decoder struct {
+	tree, data io.Reader
+	root       [blake.Size256]byte
+	start, n   uint64
+
+	size   uint64
+	lo, hi uint64
+	header bool
+	stack  []pending
+	buf    []byte
+	out    []byte
+	err    error
+}
+
+// NewDecoder returns a Decoder reading the combined encoding from r.
+func NewDecoder(r io.Reader, root [blake.Size256]byte) *Decoder {
+	return NewSliceDecoder(r, root, 0, math.MaxUint64)
+}
+
+// NewOutboardDecoder returns a Decoder reading the content from data
+// and its outboard encoding from outboard.
+func NewOutboardDecoder(data, outboard io.Reader, root [blake.Size256]byte) *Decoder {
+	d := NewDecoder(outboard, root)
+	d.data = data
+	return d
+}
+
+// NewSliceDecoder returns a Decoder reading the slice encoding of the
+// n bytes starting at start, as written by ExtractSlice, from r. It
+// returns only the requested bytes.
+func NewSliceDecoder(r io.Reader, root [blake.Size256]byte, start, n uint64) *Decoder {
+	return &Decoder{tree: r, root: root, start: start, n: n}
+}
+
+// Size returns the content length declared by the encoding. It is
+// only valid once Read has returned data or io.EOF.
+func (d *Decoder) Size() uint64 { return d.size }
+
+func (d *Decoder) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.err = d.next()
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+func (d *Decoder) check(p *pending, h [blake.Size256]byte) error {
+	if p.root {
+		h = finalize(d.size, h)
+	}
+	if h != p.hash {
+		return ErrCorrupt
+	}
+	return nil
+}
+
+func readFull(r io.Reader, b []byte) error {
+	_, err := io.ReadFull(r, b)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// next verifies the next parent or chunk of the encoding.
+func (d *Decoder) next() error {
+	if !d.header {
+		var hdr [headerSize]byte
+		if err := readFull(d.tree, hdr[:]); err != nil {
+			return err
+		}
+		d.header = true
+		d.size = binary.BigEndian.Uint64(hdr[:])
+		if d.size == 0 {
+			if finalize(0, blake.Sum256(nil)) != d.root {
+				return ErrCorrupt
+			}
+			return io.EOF
+		}
+		d.lo, d.hi = byteRange(d.size, d.start, d.n)
+		d.buf = make([]byte, ChunkSize)
+		d.stack = append(d.stack, pending{hash: d.root, n: numChunks(d.size), root: true})
+		return nil
+	}
+	if len(d.stack) == 0 {
+		return io.EOF
+	}
+	p := d.stack[len(d.stack)-1]
+	d.stack = d.stack[:len(d.stack)-1]
+	if !overlaps(p.first, p.n, d.lo, d.hi) {
+		return nil
+	}
+
+	if p.n == 1 {
+		src := d.tree
+		if d.data != nil {
+			src = d.data
+		}
+		c := d.buf[:chunkLen(d.size, p.first)]
+		if err := readFull(src, c); err != nil {
+			return err
+		}
+		if err := d.check(&p, blake.MerkleLeafHash(c)); err != nil {
+			return err
+		}
+		// Return the part of the chunk within the requested range.
+		lo, hi := p.first*ChunkSize, p.first*ChunkSize+uint64(len(c))
+		if d.start > lo {
+			lo = d.start
+		}
+		if end := d.start + d.n; end >= d.start && end < hi {
+			hi = end
+		}
+		if lo < hi {
+			d.out = c[lo-p.first*ChunkSize : hi-p.first*ChunkSize]
+		}
+		return nil
+	}
+
+	var l, r [blake.Size256]byte
+	b := d.buf[:parentSize]
+	if err := readFull(d.tree, b); err != nil {
+		return err
+	}
+	copy(l[:], b)
+	copy(r[:], b[blake.Size256:])
+	if err := d.check(&p, blake.MerkleNodeHash(l, r)); err != nil {
+		return err
+	}
+	k := split(p.n)
+	d.stack = append(d.stack,
+		pending{hash: r, first: p.first + k, n: p.n - k},
+		pending{hash: l, first: p.first, n: k})
+	return nil
+}
@@ -0,0 +1,183 @@
+package verifiedstream
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/ouzklcn/blake"
+)
+
+var sizes = []int{0, 1, ChunkSize - 1, ChunkSize, ChunkSize + 1, 5000, 10*ChunkSize + 7}
+
+func testData(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i * 7)
+	}
+	return b
+}
+
+// file is an in-memory io.WriterAt.
+type file []byte
+
+func (f *file) WriteAt(p []byte, off int64) (int, error) {
+	if end := int(off) + len(p); end > len(*f) {
+		*f = append(*f, make([]byte, end-len(*f))...)
+	}
+	return copy((*f)[off:], p), nil
+}
+
+func encode(t *testing.T, data []byte) ([]byte, [blake.Size256]byte) {
+	var f file
+	root, err := Encode(&f, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, root
+}
+
+func TestRoot(t *testing.T) {
+	data := testData(3*ChunkSize + 5)
+	tree := blake.NewMerkleTree()
+	for i := 0; i < len(data); i += ChunkSize {
+		end := i + ChunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		tree.Append(data[i:end])
+	}
+	want := finalize(uint64(len(data)), tree.Root())
+	if root, _ := Root(bytes.NewReader(data)); root != want {
+		t.Errorf("expected root %x, got %x", want, root)
+	}
+	if root, _ := Root(bytes.NewReader(data[:len(data)-1])); root == want {
+		t.Error("root does not depend on the content length")
+	}
+}
+
+func TestCombined(t *testing.T) {
+	for _, n := range sizes {
+		data := testData(n)
+		enc, root := encode(t, data)
+		if want := headerSize + n + (int(numChunks(uint64(n)))-1)*parentSize; n > 0 && len(enc) != want {
+			t.Errorf("%d: encoding is %d bytes, want %d", n, len(enc), want)
+		}
+		got, err := io.ReadAll(NewDecoder(bytes.NewReader(enc), root))
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("%d: decode failed: %v", n, err)
+		}
+		if r, _ := Root(bytes.NewReader(data)); r != root {
+			t.Errorf("%d: Root differs from the encoder's root", n)
+		}
+	}
+}
+
+func TestOutboard(t *testing.T) {
+	for _, n := range sizes {
+		data := testData(n)
+		var ob file
+		root, err := EncodeOutboard(&ob, bytes.NewReader(data), int64(n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, want := encode(t, data); root != want {
+			t.Errorf("%d: outboard root differs from the combined root", n)
+		}
+		got, err := io.ReadAll(NewOutboardDecoder(bytes.NewReader(data), bytes.NewReader(ob), root))
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("%d: outboard decode failed: %v", n, err)
+		}
+	}
+}
+
+func TestEncodeShort(t *testing.T) {
+	data := testData(3*ChunkSize + 5)
+	var f file
+	if _, err := Encode(&f, bytes.NewReader(data), int64(len(data))+1); err != io.ErrUnexpectedEOF {
+		t.Errorf("Encode: expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	f = nil
+	if _, err := EncodeOutboard(&f, bytes.NewReader(data), int64(len(data))+ChunkSize); err != io.ErrUnexpectedEOF {
+		t.Errorf("EncodeOutboard: expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	f = nil
+	if _, err := Encode(&f, bytes.NewReader(data), -1); err == nil || len(f) != 0 {
+		t.Errorf("negative size: wrote %d bytes, %v", len(f), err)
+	}
+	// Content beyond size is not encoded.
+	enc, root := encode(t, data[:ChunkSize+1])
+	f = nil
+	if r, _ := Encode(&f, bytes.NewReader(data), ChunkSize+1); r != root || !bytes.Equal(f, enc) {
+		t.Error("Encode does not stop after size bytes")
+	}
+}
+
+func TestCorruption(t *testing.T) {
+	data := testData(8 * ChunkSize)
+	enc, root := encode(t, data)
+
+	// Flip a byte in the sixth chunk, which is preceded by six parents
+	// in the pre-order layout.
+	off := headerSize + 6*parentSize + 5*ChunkSize + 10
+	enc[off] ^= 1
+	got, err := io.ReadAll(NewDecoder(bytes.NewReader(enc), root))
+	if err != ErrCorrupt {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+	if !bytes.Equal(got, data[:5*ChunkSize]) {
+		t.Errorf("expected the %d verified bytes before the corrupt chunk, got %d", 5*ChunkSize, len(got))
+	}
+
+	// A forged length must be rejected before any content.
+	enc, root = encode(t, data)
+	enc[headerSize-1]++
+	if got, err := io.ReadAll(NewDecoder(bytes.NewReader(enc), root)); err != ErrCorrupt || len(got) != 0 {
+		t.Errorf("forged length: got %d bytes, %v", len(got), err)
+	}
+
+	// A truncated encoding fails.
+	enc, root = encode(t, data)
+	if _, err := io.ReadAll(NewDecoder(bytes.NewReader(enc[:len(enc)-1]), root)); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestSlice(t *testing.T) {
+	data := testData(10*ChunkSize + 7)
+	enc, root := encode(t, data)
+	ranges := []struct{ start, n uint64 }{
+		{0, 1}, {0, uint64(len(data))}, {ChunkSize - 1, 2}, {3000, 4000},
+		{uint64(len(data)) - 3, 100}, {5 * ChunkSize, 0}, {uint64(len(data)) + 10, 5},
+	}
+	for _, r := range ranges {
+		var slice bytes.Buffer
+		if err := ExtractSlice(&slice, bytes.NewReader(enc), r.start, r.n); err != nil {
+			t.Fatal(err)
+		}
+		if r.n < ChunkSize && slice.Len() >= len(enc)/2 {
+			t.Errorf("(%d, %d): slice is %d bytes", r.start, r.n, slice.Len())
+		}
+		got, err := io.ReadAll(NewSliceDecoder(bytes.NewReader(slice.Bytes()), root, r.start, r.n))
+		if err != nil {
+			t.Errorf("(%d, %d): %v", r.start, r.n, err)
+			continue
+		}
+		lo, hi := r.start, r.start+r.n
+		if lo > uint64(len(data)) {
+			lo = uint64(len(data))
+		}
+		if hi > uint64(len(data)) {
+			hi = uint64(len(data))
+		}
+		if !bytes.Equal(got, data[lo:hi]) {
+			t.Errorf("(%d, %d): got %d bytes, want %d", r.start, r.n, len(got), hi-lo)
+		}
+
+		b := slice.Bytes()
+		b[len(b)-1] ^= 1
+		if _, err := io.ReadAll(NewSliceDecoder(bytes.NewReader(b), root, r.start, r.n)); err != ErrCorrupt {
+			t.Errorf("(%d, %d): tampered slice: %v", r.start, r.n, err)
+		}
+	}
+}
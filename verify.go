@@ -0,0 +1,103 @@
+package blake
+
+import (
+	"crypto/subtle"
+	"errors"
+	"hash"
+	"io"
+)
+
+// ErrDigestMismatch is returned by a VerifyingReader in place of io.EOF
+// when the data read does not match the expected digest.
+var ErrDigestMismatch = errors.New("blake: digest mismatch")
+
+// VerifyingReader hashes the data read through it and checks the
+// digest against an expected value once the underlying reader is
+// exhausted.
+type VerifyingReader struct {
+	r    io.Reader
+	h    hash.Hash
+	want []byte
+	err  error
+}
+
+// NewVerifyingReader returns a VerifyingReader reading from r which
+// hashes with h, such as New256(), and expects digest. At the end of
+// the stream Read returns io.EOF if the digests match in constant time
+// and ErrDigestMismatch otherwise.
+func NewVerifyingReader(r io.Reader, h hash.Hash, digest []byte) *VerifyingReader {
+	h.Reset()
+	return &VerifyingReader{r: r, h: h, want: digest}
+}
+
+func (v *VerifyingReader) Read(p []byte) (int, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+	n, err := v.r.Read(p)
+	v.h.Write(p[:n])
+	if err == io.EOF {
+		if subtle.ConstantTimeCompare(v.h.Sum(nil), v.want) != 1 {
+			err = ErrDigestMismatch
+		}
+	}
+	if err != nil {
+		v.err = err
+	}
+	return n, err
+}
+
+// Sum appends the digest of the data read so far to b.
+func (v *VerifyingReader) Sum(b []byte) []byte { return v.h.Sum(b) }
+
+// DigestWriter writes to an underlying writer while hashing everything
+// successfully written. The digest is available after Close.
+type DigestWriter struct {
+	w      io.Writer
+	h      hash.Hash
+	sum    []byte
+	closed bool
+}
+
+// NewDigestWriter returns a DigestWriter writing to w which hashes
+// with h, such as New512().
+func NewDigestWriter(w io.Writer, h hash.Hash) *DigestWriter {
+	h.Reset()
+	return &DigestWriter{w: w, h: h}
+}
+
+var errWriterClosed = errors.New("blake: write to closed DigestWriter")
+
+func (d *DigestWriter) Write(p []byte) (int, error) {
+	if d.closed {
+		return 0, errWriterClosed
+	}
+	n, err := d.w.Write(p)
+	d.h.Write(p[:n])
+	return n, err
+}
+
+// Close finalizes the digest and closes the underlying writer if it
+// implements io.Closer.
+func (d *DigestWriter) Close() error {
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	d.sum = d.h.Sum(nil)
+	if c, ok := d.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Digest returns the digest of the data written. It returns nil until
+// Close has been called.
+func (d *DigestWriter) Digest() []byte { return d.sum }
+
+// Verify reports whether the digest of the data written equals digest,
+// comparing in constant time. It returns false until Close has been
+// called.
+func (d *DigestWriter) Verify(digest []byte) bool {
+	return d.sum != nil && subtle.ConstantTimeCompare(d.sum, digest) == 1
+}
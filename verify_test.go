@@ -0,0 +1,64 @@
+package blake
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestVerifyingReader(t *testing.T) {
+	data := strings.Repeat("BLAKE", 1000)
+	want := Sum256([]byte(data))
+	got, err := io.ReadAll(NewVerifyingReader(strings.NewReader(data), New256(), want[:]))
+	if err != nil || string(got) != data {
+		t.Errorf("ReadAll = %d bytes, %v", len(got), err)
+	}
+
+	want[0] ^= 1
+	r := NewVerifyingReader(strings.NewReader(data), New256(), want[:])
+	if _, err := io.ReadAll(r); err != ErrDigestMismatch {
+		t.Errorf("expected ErrDigestMismatch, got %v", err)
+	}
+	if _, err := r.Read(make([]byte, 1)); err != ErrDigestMismatch {
+		t.Errorf("expected sticky ErrDigestMismatch, got %v", err)
+	}
+
+	sum512 := Sum512([]byte(data))
+	if _, err := io.ReadAll(NewVerifyingReader(strings.NewReader(data[1:]), New512(), sum512[:])); err != ErrDigestMismatch {
+		t.Errorf("expected ErrDigestMismatch for truncated data, got %v", err)
+	}
+}
+
+type closeRecorder struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestDigestWriter(t *testing.T) {
+	var out closeRecorder
+	w := NewDigestWriter(&out, New512())
+	io.WriteString(w, "Go")
+	io.WriteString(w, "lang")
+	if w.Digest() != nil {
+		t.Error("digest available before Close")
+	}
+	if err := w.Close(); err != nil || !out.closed {
+		t.Fatalf("Close = %v, underlying closed %v", err, out.closed)
+	}
+	want := Sum512([]byte("Golang"))
+	if !bytes.Equal(w.Digest(), want[:]) || !w.Verify(want[:]) {
+		t.Errorf("expected digest %x, got %x", want, w.Digest())
+	}
+	if out.String() != "Golang" {
+		t.Errorf("underlying writer got %q", out.String())
+	}
+	if _, err := w.Write([]byte("x")); err == nil {
+		t.Error("expected error writing after Close")
+	}
+}